@@ -0,0 +1,100 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// approval is the record kept of a .dependency_license line, from fields
+// in its comment such as
+// `# approved-by: jdoe; reason: bundled font; expires: 2026-12-31`.
+type approval struct {
+	File       string
+	Line       int
+	Pattern    string
+	License    License
+	ApprovedBy string
+	Reason     string
+	Expires    time.Time /* Zero if the approval never lapses. */
+}
+
+// approvals are those of the .dependency_license files loaded, in order.
+var approvals []approval
+
+// parseApproval reads the fields of an override's comment. A comment
+// without fields is a plain remark.
+func parseApproval(comment string) (approval, error) {
+	var a approval
+	for _, field := range strings.Split(comment, `;`) {
+		parts := strings.SplitN(field, `:`, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+		switch key {
+		case `approved-by`:
+			a.ApprovedBy = value
+		case `reason`:
+			a.Reason = value
+		case `expires`:
+			t, err := time.ParseInLocation(`2006-01-02`, value, time.Local)
+			if err != nil {
+				return a, errors.New("expires must be a date such as 2006-01-02: " + value)
+			}
+			a.Expires = t
+		}
+	}
+	return a, nil
+}
+
+// expired tells whether the approval lapsed before today.
+func (a approval) expired() bool {
+	return !a.Expires.IsZero() && !time.Now().Before(a.Expires.AddDate(0, 0, 1))
+}
+
+// audit prints every approval, flagging those which have lapsed and those
+// not saying who approved them and why. It returns the exit status: 1 if
+// any approval lacks attribution.
+func audit(w io.Writer) int {
+	code := 0
+	for _, a := range approvals {
+		status, state := ``, `Approved`
+		switch {
+		case a.ApprovedBy == `` || a.Reason == ``:
+			status, state = `Error`, `Unattributed!`
+			code = 1
+		case a.expired():
+			state = `Expired ` + a.Expires.Format(`2006-01-02`)
+		case !a.Expires.IsZero():
+			state = `Until ` + a.Expires.Format(`2006-01-02`)
+		}
+		desc := fmt.Sprintf("%s:%d %s, %s", a.File, a.Line, a.Pattern, a.License)
+		if a.ApprovedBy != `` {
+			desc += ` by ` + a.ApprovedBy
+		}
+		if a.Reason != `` {
+			desc += `: ` + a.Reason
+		}
+		fmt.Fprintf(w, "%-6s%40s %s\n", status, state, desc)
+	}
+	return code
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// attestationFile is where the in-toto statement of the results is
+// written, and signAttestation has cosign sign it keylessly.
+var (
+	attestationFile string
+	signAttestation bool
+)
+
+// predicateType names what the predicate of the statement is: the report,
+// as `--format json` prints it.
+const predicateType = `https://github.com/comcast/weasel/report/v` + schemaVersion
+
+type attestationSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// statement is an in-toto Statement, attesting that the report describes
+// the files which are its subjects.
+type statement struct {
+	Type          string               `json:"_type"`
+	Subject       []attestationSubject `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Report               `json:"predicate"`
+}
+
+// writeAttestation writes the statement of the report to attestationFile,
+// each file reported a subject with its SHA-256. Files which could not be
+// read, and so have no digest, are left out.
+func writeAttestation(r Report) error {
+	st := statement{
+		Type:          `https://in-toto.io/Statement/v1`,
+		Subject:       []attestationSubject{},
+		PredicateType: predicateType,
+		Predicate:     r,
+	}
+	for _, res := range r.Files {
+		if res.SHA256 != `` {
+			st.Subject = append(st.Subject, attestationSubject{res.Path, map[string]string{`sha256`: res.SHA256}})
+		}
+	}
+	f, err := os.Create(attestationFile)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent(``, `  `)
+	err = enc.Encode(st)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// signStatement signs the statement with cosign, keylessly by way of
+// Sigstore, writing the signature, certificate and transparency log entry
+// beside it as a bundle. Like --db, it needs the command, not a library.
+func signStatement() error {
+	if _, err := exec.LookPath(`cosign`); err != nil {
+		return errors.New("the cosign command is required for --sign-attestation")
+	}
+	cmd := exec.Command(`cosign`, `sign-blob`, `--yes`, `--bundle`, attestationFile+`.sigstore.json`, attestationFile)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.New(strings.TrimSpace(string(out)) + ": " + err.Error())
+	}
+	return nil
+}
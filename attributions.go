@@ -0,0 +1,232 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// attribution is everything the notices say about one license.
+type attribution struct {
+	components []string /* Vendored packages, or files outside any. */
+	copyrights []string
+	textFile   string
+	text       string
+}
+
+// thirdParty returns the licenses of a file which the project doesn't
+// expect of its own files, or all of them if the file is vendored.
+func thirdParty(name string, lics []License) []License {
+	if Has(lics, License(`Ignore`)) {
+		return nil
+	}
+	expected := expectedLicense(name)
+	vendored := Vendored(name)
+	var third []License
+	for _, lic := range lics {
+		base, _ := lic.split()
+		if !countable(base) {
+			continue
+		}
+		if base != expected || vendored {
+			third = append(third, base)
+		}
+	}
+	return third
+}
+
+// copyrightLines returns the lines of a file's comments which name a
+// copyright holder.
+func copyrightLines(name string) []string {
+	f, err := openSource(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []string
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		line := commentText(s.Text())
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, `copyright `) || strings.HasPrefix(lower, `(c) `) || strings.HasPrefix(line, `©`) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// attributions groups the third-party files by license, with their
+// copyright lines and a full text of the license: one found among them,
+// or the reference text of a custom license.
+func attributions(files map[string][]License) map[License]*attribution {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	byLicense := make(map[License]*attribution)
+	for _, name := range names {
+		third := thirdParty(name, files[name])
+		for _, lic := range third {
+			a, ok := byLicense[lic]
+			if !ok {
+				a = &attribution{}
+				byLicense[lic] = a
+			}
+			component := vendoredRoot(name)
+			if component == `` {
+				component = filepath.ToSlash(name)
+			}
+			if len(a.components) == 0 || a.components[len(a.components)-1] != component {
+				a.components = append(a.components, component)
+			}
+			a.copyrights = append(a.copyrights, copyrightLines(name)...)
+			if a.text == `` && licenseLike(name) && len(third) == 1 {
+				if b, err := readFile(name); err == nil {
+					a.textFile, a.text = filepath.ToSlash(name), string(b)
+				}
+			}
+			if a.text == `` {
+				if cfg := configFor(name); cfg != nil && cfg.Licenses[lic] != `` {
+					if b, err := cfg.readText(lic); err == nil {
+						a.textFile, a.text = filepath.ToSlash(cfg.textFile(lic)), string(b)
+					}
+				}
+			}
+		}
+	}
+	for _, a := range byLicense {
+		sort.Strings(a.copyrights)
+		var uniq []string
+		for _, line := range a.copyrights {
+			if len(uniq) == 0 || uniq[len(uniq)-1] != line {
+				uniq = append(uniq, line)
+			}
+		}
+		a.copyrights = uniq
+	}
+	return byLicense
+}
+
+// writeAttributions prints the third-party notices as Markdown.
+func writeAttributions(w io.Writer, files map[string][]License) error {
+	byLicense := attributions(files)
+	var lics []License
+	for lic := range byLicense {
+		lics = append(lics, lic)
+	}
+	sort.Sort(Licenses(lics))
+
+	var lines []string
+	lines = append(lines, `# Third-Party Notices`, ``)
+	if len(lics) == 0 {
+		lines = append(lines, `This project includes no third-party software.`)
+	} else {
+		lines = append(lines, `This project includes third-party software under the following licenses.`)
+	}
+	for _, lic := range lics {
+		a := byLicense[lic]
+		name := lic
+		if useSPDX {
+			name = lic.SPDX()
+		}
+		lines = append(lines, ``, `## `+string(name), ``)
+		for _, component := range a.components {
+			lines = append(lines, "- `"+component+"`")
+		}
+		if len(a.copyrights) > 0 {
+			lines = append(lines, ``)
+			for _, line := range a.copyrights {
+				lines = append(lines, `    `+line)
+			}
+		}
+		lines = append(lines, ``)
+		if a.text == `` {
+			text := "No text of this license was found among these files."
+			if id := lic.SPDX(); spdxListed(string(id)) {
+				text += " See https://spdx.org/licenses/" + string(id) + ".html."
+			}
+			lines = append(lines, text)
+			continue
+		}
+		lines = append(lines, "The text of the license, from `"+a.textFile+"`:", ``)
+		for _, line := range strings.Split(strings.TrimRight(a.text, "\n"), "\n") {
+			if strings.TrimSpace(line) == `` {
+				lines = append(lines, ``)
+			} else {
+				lines = append(lines, `    `+strings.TrimRight(line, "\r"))
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// writeBinaryLicense prints the project's LICENSE followed by the license
+// of each bundled third-party component, as the ASF asks of convenience
+// binaries. Each distinct text appears once.
+func writeBinaryLicense(w io.Writer, files map[string][]License) error {
+	own, err := readFile(`LICENSE`)
+	if err != nil {
+		return err
+	}
+	byLicense := attributions(files)
+	var lics []License
+	for lic := range byLicense {
+		lics = append(lics, lic)
+	}
+	sort.Sort(Licenses(lics))
+
+	seen := map[uint64]struct{}{textHash(own): {}}
+	out := []string{strings.TrimRight(string(own), "\n")}
+	for _, lic := range lics {
+		a := byLicense[lic]
+		name := lic
+		if useSPDX {
+			name = lic.SPDX()
+		}
+		out = append(out, ``, strings.Repeat(`-`, 80), ``, "This product bundles the following components, under the "+string(name)+" license:", ``)
+		for _, component := range a.components {
+			out = append(out, `    `+component)
+		}
+		out = append(out, ``)
+		hash := textHash([]byte(a.text))
+		if _, ok := seen[hash]; ok && a.text != `` {
+			out = append(out, `The text of this license is above.`)
+			continue
+		}
+		if a.text == `` {
+			text := "No text of this license was found among these components."
+			if id := lic.SPDX(); spdxListed(string(id)) {
+				text += " See https://spdx.org/licenses/" + string(id) + ".html."
+			}
+			out = append(out, text)
+			continue
+		}
+		seen[hash] = struct{}{}
+		out = append(out, strings.TrimRight(a.text, "\n"))
+	}
+	_, err = fmt.Fprintln(w, strings.Join(out, "\n"))
+	return err
+}
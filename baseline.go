@@ -0,0 +1,140 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// baselineFile lists the errors accepted as they stand, for --baseline.
+var baselineFile string
+
+// baselineEntry is a file whose errors are accepted only for as long as
+// neither its content nor its licenses change.
+type baselineEntry struct {
+	Path     string    `json:"path"`
+	Licenses []License `json:"licenses"`
+	SHA256   string    `json:"sha256"`
+}
+
+type baseline struct {
+	SchemaVersion string          `json:"schemaVersion"`
+	Files         []baselineEntry `json:"files"`
+}
+
+// fileDigest is the SHA-256 of a file's content, in hex.
+func fileDigest(name string) (string, error) {
+	f, err := openFile(name)
+	if err != nil {
+		return ``, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ``, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeBaseline records every file in error, with a digest of its content.
+func writeBaseline(w io.Writer, files map[string][]License) error {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := baseline{SchemaVersion: schemaVersion, Files: []baselineEntry{}}
+	for _, name := range names {
+		licStr, ignore, undoc := describe(files[name])
+		if ignore || !undoc || isReadError(licStr) {
+			continue
+		}
+		digest, err := fileDigest(name)
+		if err != nil {
+			return err
+		}
+		b.Files = append(b.Files, baselineEntry{name, files[name], digest})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent(``, `  `)
+	return enc.Encode(b)
+}
+
+// readBaseline reads the baseline file name.
+func readBaseline(name string) (*baseline, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, errors.New(name + ": " + err.Error())
+	}
+	return &b, nil
+}
+
+// applyBaseline accepts the errors of each file in the baseline which is
+// unchanged since, tagging it `Baselined`. A file whose content or
+// licenses have changed is checked as any other.
+func applyBaseline(name string, files map[string][]License) error {
+	b, err := readBaseline(name)
+	if err != nil {
+		return err
+	}
+	b.apply(name, files)
+	return nil
+}
+
+// apply is applyBaseline for the baseline already read from name.
+func (b *baseline) apply(name string, files map[string][]License) {
+	/* The baseline names licenses, but is no source of them. */
+	source := name
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, name); err == nil {
+			delete(files, rel)
+			source = rel
+		}
+	}
+	for _, entry := range b.Files {
+		lics, ok := files[entry.Path]
+		if !ok || !sameLicenses(Uniq(lics), Uniq(entry.Licenses)) {
+			continue
+		}
+		if digest, err := fileDigest(entry.Path); err != nil || digest != entry.SHA256 {
+			continue
+		}
+		var accepted []License
+		if len(lics) == 0 {
+			accepted = []License{License(`Unknown`)}
+		}
+		for _, lic := range lics {
+			accepted = append(accepted, License(strings.TrimSuffix(string(lic), `!`)))
+		}
+		files[entry.Path] = append(accepted, License(`Baselined`))
+		recordSuppression(entry.Path, Suppression{Kind: `baseline`, Source: filepath.ToSlash(source), Entry: entry.SHA256})
+	}
+}
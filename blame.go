@@ -0,0 +1,133 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// commitInfo is a commit which touched a file, and the file's name in it.
+type commitInfo struct {
+	Hash    string
+	Author  string
+	Date    string
+	Subject string
+	Name    string
+}
+
+// fileCommits lists the commits which touched name, newest first, following
+// renames.
+func fileCommits(name string) ([]commitInfo, error) {
+	b, err := exec.Command(`git`, `log`, `--follow`, `--date=short`, `--name-only`,
+		`--format=%x01%H%x00%an <%ae>%x00%ad%x00%s`, `--`, name).Output()
+	if err != nil {
+		return nil, errors.New("cannot read history of " + name + ": " + err.Error())
+	}
+
+	var commits []commitInfo
+	for _, rec := range strings.Split(string(b), "\x01")[1:] {
+		lines := strings.Split(strings.TrimSpace(rec), "\n")
+		fields := strings.Split(lines[0], "\x00")
+		if len(fields) != 4 {
+			continue
+		}
+		c := commitInfo{Hash: fields[0], Author: fields[1], Date: fields[2], Subject: fields[3]}
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != `` {
+				c.Name = line
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// blob is the contents of a file as of a commit, read as a file is.
+type blob struct{ *bytes.Reader }
+
+func (blob) Close() error { return nil }
+
+// licensesAt identifies the licenses of the file as of the given commit,
+// decompressing and extracting its text as for the file in the worktree.
+func licensesAt(c commitInfo) ([]License, bool) {
+	b, err := exec.Command(`git`, `show`, c.Hash+`:`+c.Name).Output()
+	if err != nil {
+		return nil, false
+	}
+	lics, _, err := identifyContent(c.Name, blob{bytes.NewReader(b)})
+	if err != nil {
+		return nil, false
+	}
+	return Uniq(lics), true
+}
+
+func sameLicenses(a, b []License) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// blame finds the commit since which the file has borne the licenses it
+// bears now, which for a file lacking a header is the commit that broke it.
+func blame(w io.Writer, name string) error {
+	if !hasGit {
+		return errors.New("git is required for blame")
+	}
+
+	current, err := fileLicenses(name)
+	if err != nil {
+		return err
+	}
+	current = Uniq(current)
+
+	commits, err := fileCommits(name)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return errors.New(name + " has never been committed")
+	}
+
+	since := -1
+	for i, c := range commits {
+		lics, ok := licensesAt(c)
+		if !ok || !sameLicenses(lics, current) {
+			break
+		}
+		since = i
+	}
+
+	licStr, _, _ := describe(current)
+	if since < 0 {
+		fmt.Fprintf(w, "%s: %s, not yet committed\n", name, licStr)
+		return nil
+	}
+	c := commits[since]
+	fmt.Fprintf(w, "%s: %s since %.12s (%s, %s) %s\n", name, licStr, c.Hash, c.Author, c.Date, c.Subject)
+	return nil
+}
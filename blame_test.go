@@ -0,0 +1,101 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const apacheHeader = "Licensed under the Apache License, Version 2.0 (the \"License\");\nyou may not use this file except in compliance with the License.\n"
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// commit writes the files and commits them with the subject.
+func commit(t *testing.T, subject string, files map[string][]byte) {
+	t.Helper()
+	for name, content := range files {
+		if err := ioutil.WriteFile(name, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{`add`, `.`}, {`commit`, `-q`, `-m`, subject}} {
+		cmd := exec.Command(`git`, append([]string{`-c`, `user.name=Test`, `-c`, `user.email=test@example.com`}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, ` `), err, out)
+		}
+	}
+}
+
+func TestBlame(t *testing.T) {
+	if _, err := exec.LookPath(`git`); err != nil {
+		t.Skip("git is required for blame")
+	}
+	inTree(t, nil)
+	if out, err := exec.Command(`git`, `init`, `-q`).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	defer func(had bool) { hasGit = had }(hasGit)
+	hasGit = true
+	commit(t, `Add notes`, map[string][]byte{
+		`notes.txt`:     []byte(apacheHeader + "Notes.\n"),
+		`notes.txt.gz`:  gzipped(t, apacheHeader+"Notes.\n"),
+		`broken.txt.gz`: gzipped(t, apacheHeader+"Notes.\n"),
+	})
+	commit(t, `Drop the header`, map[string][]byte{
+		`broken.txt.gz`: gzipped(t, "Notes.\n"),
+	})
+	commit(t, `Add other notes`, map[string][]byte{
+		`other.txt`: []byte(apacheHeader),
+	})
+
+	tests := []struct {
+		name     string
+		licenses string
+		subject  string
+	}{
+		{`notes.txt`, `Apache`, `Add notes`},
+		{`notes.txt.gz`, `Apache`, `Add notes`},
+		{`broken.txt.gz`, `Unknown!`, `Drop the header`},
+	}
+	for _, tc := range tests {
+		var out bytes.Buffer
+		if err := blame(&out, tc.name); err != nil {
+			t.Errorf("blame %s: %v", tc.name, err)
+			continue
+		}
+		got := out.String()
+		if !strings.HasPrefix(got, tc.name+`: `+tc.licenses+` since `) || !strings.HasSuffix(got, `) `+tc.subject+"\n") {
+			t.Errorf("blame %s = %q, want %s since the commit %q", tc.name, got, tc.licenses, tc.subject)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sourceMap is the part of a source map bearing the original sources: the
+// paths of the modules bundled, and their contents if embedded. An index
+// map instead has sections, each with a map of its own.
+type sourceMap struct {
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent"`
+	Sections       []struct {
+		Map *sourceMap `json:"map"`
+	} `json:"sections"`
+}
+
+// isBundle reports whether a file may be a bundle of scripts or styles,
+// which may refer to its source map and to the licenses extracted from it.
+func isBundle(lower string) bool {
+	for _, ext := range []string{`.js`, `.mjs`, `.cjs`, `.css`} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// bundleReference matches a bundle's comment giving its source map, and
+// that webpack leaves in place of the license comments it extracts.
+var bundleReference = regexp.MustCompile(`[#@] sourceMappingURL=(\S+)|For license information please see (\S+?)(?:\s|\*/|$)`)
+
+// sourceMapText returns the sources bundled according to a source map,
+// each after a line of its path, so that the licenses of the modules
+// bundled are found. It fails for anything that doesn't parse as a source
+// map, which is then read as it stands.
+func sourceMapText(b []byte) ([]byte, bool) {
+	var sm sourceMap
+	if err := json.Unmarshal(b, &sm); err != nil || (sm.Sources == nil && sm.Sections == nil) {
+		return nil, false
+	}
+	var text bytes.Buffer
+	sm.write(&text)
+	return text.Bytes(), true
+}
+
+func (sm *sourceMap) write(text *bytes.Buffer) {
+	for i, source := range sm.Sources {
+		text.WriteString(source)
+		text.WriteString("\n")
+		if i < len(sm.SourcesContent) && sm.SourcesContent[i] != nil {
+			text.WriteString(*sm.SourcesContent[i])
+			text.WriteString("\n")
+		}
+	}
+	for _, section := range sm.Sections {
+		if section.Map != nil {
+			section.Map.write(text)
+		}
+	}
+}
+
+// bundleText returns a bundle followed by the sources of its source map,
+// whether inline or beside it, and by the license comments webpack
+// extracted from it to a file beside it. A bundle referring to neither is
+// read as it stands.
+func bundleText(name string, b []byte) ([]byte, bool) {
+	refs := bundleReference.FindAllSubmatch(b, -1)
+	if refs == nil {
+		return nil, false
+	}
+	text := bytes.NewBuffer(b)
+	for _, ref := range refs {
+		if len(ref[1]) != 0 {
+			if sm, ok := bundleFile(name, string(ref[1])); ok {
+				if sources, ok := sourceMapText(sm); ok {
+					text.WriteString("\n")
+					text.Write(sources)
+				}
+			}
+		} else if licenses, ok := bundleFile(name, string(ref[2])); ok {
+			text.WriteString("\n")
+			text.Write(licenses)
+		}
+	}
+	return text.Bytes(), true
+}
+
+// bundleFile reads the file a bundle refers to: a data URL, or a relative
+// one to a file in the bundle's directory or beneath it. Remote files are
+// not fetched.
+func bundleFile(name, ref string) ([]byte, bool) {
+	if strings.HasPrefix(ref, `data:`) {
+		comma := strings.IndexByte(ref, ',')
+		if comma < 0 {
+			return nil, false
+		}
+		if strings.HasSuffix(ref[:comma], `;base64`) {
+			b, err := base64.StdEncoding.DecodeString(ref[comma+1:])
+			return b, err == nil
+		}
+		s, err := url.PathUnescape(ref[comma+1:])
+		return []byte(s), err == nil
+	}
+	ref, err := url.PathUnescape(ref)
+	if err != nil || strings.Contains(ref, `:`) || strings.HasPrefix(ref, `/`) {
+		return nil, false
+	}
+	ref = filepath.FromSlash(strings.SplitN(ref, `?`, 2)[0])
+	if clean := filepath.Clean(ref); clean == `..` || strings.HasPrefix(clean, `..`+string(filepath.Separator)) {
+		return nil, false
+	}
+	f, err := openThrottled(name, filepath.Join(filepath.Dir(name), ref))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	return b, err == nil
+}
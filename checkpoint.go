@@ -0,0 +1,218 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// checkpointFile is where the progress of a scan is saved from time to
+// time, so an interrupted scan can resume instead of starting over.
+var checkpointFile string
+
+// checkpointInterval is how often the progress is saved.
+const checkpointInterval = 30 * time.Second
+
+// checkpoint is the progress of a scan: the identification of every file
+// identified so far, by absolute path. It is only resumed from by a run
+// given the same arguments, and an entry only for a file of the same size
+// and modification time.
+type checkpoint struct {
+	Args  []string                   `json:"args"`
+	Files map[string]checkpointEntry `json:"files"`
+}
+
+type checkpointEntry struct {
+	Size     int64      `json:"size"`
+	ModTime  time.Time  `json:"modTime"`
+	Licenses []License  `json:"licenses"`
+	Evidence []Evidence `json:"evidence,omitempty"`
+}
+
+var progress = struct {
+	sync.Mutex
+	resumed checkpoint
+	saved   checkpoint
+	dirty   bool
+	stop    chan struct{}
+	done    chan struct{}
+}{}
+
+// startCheckpoint loads the progress of an earlier run, if any, and saves
+// the progress of this one every checkpointInterval until
+// finishCheckpoint.
+func startCheckpoint() error {
+	if checkpointFile == `` {
+		return nil
+	}
+	args := os.Args[1:]
+	progress.resumed = checkpoint{Files: make(map[string]checkpointEntry)}
+	progress.saved = checkpoint{Args: args, Files: make(map[string]checkpointEntry)}
+	b, err := ioutil.ReadFile(checkpointFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		/* The file is removed once the scan completes, so be sure it is one. */
+		var c checkpoint
+		if err := json.Unmarshal(b, &c); err != nil || c.Files == nil {
+			return errors.New(checkpointFile + " is not a checkpoint")
+		}
+		if sameArgs(c.Args, args) {
+			progress.resumed = c
+		}
+	}
+
+	progress.stop = make(chan struct{})
+	progress.done = make(chan struct{})
+	go func() {
+		defer close(progress.done)
+		ticker := time.NewTicker(checkpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := saveCheckpoint(); err != nil {
+					fmt.Fprintln(os.Stderr, "Cannot save checkpoint: "+err.Error())
+				}
+			case <-progress.stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func sameArgs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// finishCheckpoint stops saving the progress. Once the scan is complete
+// the checkpoint is removed, as there is nothing left to resume; else it
+// is saved a last time.
+func finishCheckpoint(complete bool) {
+	if checkpointFile == `` {
+		return
+	}
+	close(progress.stop)
+	<-progress.done
+	if complete {
+		os.Remove(checkpointFile)
+		return
+	}
+	if err := saveCheckpoint(); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot save checkpoint: "+err.Error())
+	}
+}
+
+// resumedIdentification returns the identification of name saved by an
+// earlier run, if the file hasn't changed since.
+func resumedIdentification(name string) ([]License, []Evidence, bool) {
+	if checkpointFile == `` || sourceFS != nil {
+		return nil, nil, false
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, nil, false
+	}
+	progress.Lock()
+	entry, ok := progress.resumed.Files[abs]
+	progress.Unlock()
+	if !ok {
+		return nil, nil, false
+	}
+	info, err := os.Stat(name)
+	if err != nil || info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+		return nil, nil, false
+	}
+	recordProgress(abs, entry)
+	return append([]License(nil), entry.Licenses...), entry.Evidence, true
+}
+
+// checkpointIdentified records the identification of name in the progress
+// to be saved.
+func checkpointIdentified(name string, licenses []License, evidence []Evidence) {
+	if checkpointFile == `` || sourceFS != nil {
+		return
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return
+	}
+	recordProgress(abs, checkpointEntry{
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Licenses: append([]License(nil), licenses...),
+		Evidence: evidence,
+	})
+}
+
+func recordProgress(abs string, entry checkpointEntry) {
+	progress.Lock()
+	defer progress.Unlock()
+	progress.saved.Files[abs] = entry
+	progress.dirty = true
+}
+
+// saveCheckpoint writes the progress to checkpointFile, by way of a
+// temporary file so an interruption never leaves half of it.
+func saveCheckpoint() error {
+	progress.Lock()
+	if !progress.dirty {
+		progress.Unlock()
+		return nil
+	}
+	b, err := json.Marshal(progress.saved)
+	progress.dirty = false
+	progress.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(checkpointFile), `.weasel-checkpoint`)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), checkpointFile)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// githubCheck posts the results as a GitHub Check Run.
+var githubCheck bool
+
+// checkAnnotationLimit is the most annotations GitHub accepts per request;
+// the rest are added by updating the run.
+const checkAnnotationLimit = 50
+
+type checkAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title"`
+	Message         string `json:"message"`
+}
+
+type checkOutput struct {
+	Title       string            `json:"title"`
+	Summary     string            `json:"summary"`
+	Annotations []checkAnnotation `json:"annotations,omitempty"`
+}
+
+type checkRun struct {
+	ID         int64       `json:"id,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	HeadSHA    string      `json:"head_sha,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	Conclusion string      `json:"conclusion,omitempty"`
+	Output     checkOutput `json:"output"`
+}
+
+// checkSHA is the commit the check is for: the head of the pull request
+// when run for one, since GITHUB_SHA is then a merge commit.
+func checkSHA() string {
+	if name := os.Getenv(`GITHUB_EVENT_PATH`); name != `` {
+		var event struct {
+			PullRequest struct {
+				Head struct {
+					SHA string `json:"sha"`
+				} `json:"head"`
+			} `json:"pull_request"`
+		}
+		if b, err := ioutil.ReadFile(name); err == nil && json.Unmarshal(b, &event) == nil && event.PullRequest.Head.SHA != `` {
+			return event.PullRequest.Head.SHA
+		}
+	}
+	if sha := os.Getenv(`GITHUB_SHA`); sha != `` {
+		return sha
+	}
+	return revision()
+}
+
+// postCheck reports the violations as a completed Check Run named weasel,
+// annotating each file, using the token and repository GitHub Actions
+// provides in GITHUB_TOKEN and GITHUB_REPOSITORY.
+func postCheck(violations []violation, conclusion string, code int) error {
+	token := os.Getenv(`GITHUB_TOKEN`)
+	repo := os.Getenv(`GITHUB_REPOSITORY`)
+	if token == `` || repo == `` {
+		return errors.New("GITHUB_TOKEN and GITHUB_REPOSITORY must be set")
+	}
+	sha := checkSHA()
+	if sha == `` {
+		return errors.New("cannot tell which commit to check; set GITHUB_SHA")
+	}
+	api := os.Getenv(`GITHUB_API_URL`)
+	if api == `` {
+		api = `https://api.github.com`
+	}
+	url := strings.TrimSuffix(api, `/`) + `/repos/` + repo + `/check-runs`
+
+	var annotations []checkAnnotation
+	for _, v := range violations {
+		a := checkAnnotation{
+			Path:            v.Path,
+			StartLine:       1,
+			EndLine:         1,
+			AnnotationLevel: `failure`,
+			Title:           withCodes(v.Licenses, v.Codes),
+			Message:         v.Licenses + " is not documented for this file.",
+		}
+		if v.Licenses == `Extra-License!` {
+			a.Path = `LICENSE`
+			a.Message = "No file matches @" + v.Path + "."
+		} else if strings.HasPrefix(v.Licenses, `Stale-`) {
+			parts := strings.SplitN(v.Path, `: `, 2)
+			a.Path, a.StartLine = staleLocation(Suppression{Source: parts[0]})
+			a.EndLine = a.StartLine
+			a.Message = "Nothing matches " + parts[len(parts)-1] + "."
+		} else if strings.HasPrefix(v.Licenses, `Unknown`) || isReadError(v.Licenses) {
+			a.AnnotationLevel = `warning`
+			a.Message = "weasel could not identify the license of this file."
+		}
+		annotations = append(annotations, a)
+	}
+
+	run := checkRun{
+		Name:       `weasel`,
+		HeadSHA:    sha,
+		Status:     `completed`,
+		Conclusion: `success`,
+	}
+	run.Output.Title = "No license violations"
+	if code != 0 {
+		run.Conclusion = `failure`
+	}
+	if len(violations) > 0 {
+		run.Output.Title = fmt.Sprintf("%d license violations", len(violations))
+	}
+	run.Output.Summary = "Repository license: " + conclusion
+
+	var id int64
+	for first := true; first || len(annotations) > 0; first = false {
+		n := len(annotations)
+		if n > checkAnnotationLimit {
+			n = checkAnnotationLimit
+		}
+		run.Output.Annotations, annotations = annotations[:n], annotations[n:]
+		method := `POST`
+		runURL := url
+		if !first {
+			/* Only the output may change once the run exists. */
+			method = `PATCH`
+			runURL = url + `/` + strconv.FormatInt(id, 10)
+			run = checkRun{Output: run.Output}
+		}
+		var err error
+		if id, err = sendCheck(method, runURL, token, run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendCheck creates or updates a Check Run, returning its id.
+func sendCheck(method, url, token string, run checkRun) (int64, error) {
+	body, err := json.Marshal(run)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(`Authorization`, `Bearer `+token)
+	req.Header.Set(`Accept`, `application/vnd.github+json`)
+	req.Header.Set(`Content-Type`, `application/json`)
+
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, errors.New("GitHub responded " + resp.Status)
+	}
+	var created checkRun
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// findingLine is the line a finding is reported against: the start of the
+// phrase which identified the first undocumented license, or else 1.
+func findingLine(res FileResult) int {
+	for _, ev := range res.Evidence {
+		if Has(res.Licenses, ev.License+`!`) && ev.StartLine > 0 {
+			return ev.StartLine
+		}
+	}
+	return 1
+}
+
+// findingMessage gives a file's licenses as the text report would, where
+// those marked ! are the problem, and the codes of its findings.
+func findingMessage(res FileResult) string {
+	var lics []string
+	for _, lic := range res.Licenses {
+		lics = append(lics, string(lic))
+	}
+	return withCodes("weasel reports "+strings.Join(lics, ` `), res.Codes)
+}
+
+var azdoProperty = strings.NewReplacer(`%`, `%AZP25`, "\r", `%0D`, "\n", `%0A`, `;`, `%3B`, `]`, `%5D`)
+var azdoMessage = strings.NewReplacer(`%`, `%AZP25`, "\r", `%0D`, "\n", `%0A`)
+
+// writeAzDO prints each error as an Azure DevOps logging command, which
+// the pipeline shows as an issue on the file.
+func writeAzDO(w io.Writer, r Report) error {
+	for _, res := range r.Files {
+		if !res.Error {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "##vso[task.logissue type=error;sourcepath=%s;linenumber=%d;code=%s;]%s\n", azdoProperty.Replace(res.Path), findingLine(res), azdoProperty.Replace(strings.Join(res.Codes, `,`)), azdoMessage.Replace(findingMessage(res))); err != nil {
+			return err
+		}
+	}
+	for _, extra := range r.ExtraLicenses {
+		if _, err := fmt.Fprintf(w, "##vso[task.logissue type=error;sourcepath=%s;code=WSL003;]%s\n", azdoProperty.Replace(displayPath(`LICENSE`)), azdoMessage.Replace(withCodes("No file matches @"+extra+".", []string{`WSL003`}))); err != nil {
+			return err
+		}
+	}
+	for _, st := range r.Stale {
+		file, line := staleLocation(st)
+		if _, err := fmt.Fprintf(w, "##vso[task.logissue type=error;sourcepath=%s;linenumber=%d;code=%s;]%s\n", azdoProperty.Replace(displayPath(file)), line, st.Code, azdoMessage.Replace(withCodes(staleFinding(st)+" "+st.Entry, []string{st.Code}))); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "Repository license: "+r.Conclusion)
+	return err
+}
+
+var teamcityValue = strings.NewReplacer(`|`, `||`, `'`, `|'`, "\n", `|n`, "\r", `|r`, `[`, `|[`, `]`, `|]`)
+
+// writeTeamCity prints each error as a TeamCity inspection, and a build
+// problem if the run failed.
+func writeTeamCity(w io.Writer, r Report) error {
+	lines := []string{`##teamcity[inspectionType id='weasel' name='License' category='License' description='Licenses which are not documented']`}
+	for _, res := range r.Files {
+		if res.Error {
+			lines = append(lines, fmt.Sprintf("##teamcity[inspection typeId='weasel' message='%s' file='%s' line='%d' SEVERITY='ERROR']", teamcityValue.Replace(findingMessage(res)), teamcityValue.Replace(res.Path), findingLine(res)))
+		}
+	}
+	for _, extra := range r.ExtraLicenses {
+		lines = append(lines, fmt.Sprintf("##teamcity[inspection typeId='weasel' message='%s' file='%s' SEVERITY='ERROR']", teamcityValue.Replace(withCodes("No file matches @"+extra+".", []string{`WSL003`})), teamcityValue.Replace(displayPath(`LICENSE`))))
+	}
+	for _, st := range r.Stale {
+		file, line := staleLocation(st)
+		lines = append(lines, fmt.Sprintf("##teamcity[inspection typeId='weasel' message='%s' file='%s' line='%d' SEVERITY='ERROR']", teamcityValue.Replace(withCodes(staleFinding(st)+" "+st.Entry, []string{st.Code})), teamcityValue.Replace(displayPath(file)), line))
+	}
+	if r.Failed {
+		lines = append(lines, "##teamcity[buildProblem description='weasel found license violations' identity='weasel']")
+	}
+	lines = append(lines, fmt.Sprintf("##teamcity[buildStatus text='{build.status.text}; repository license: %s']", teamcityValue.Replace(r.Conclusion)))
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+var tapDescription = strings.NewReplacer(`\`, `\\`, `#`, `\#`, "\n", ` `)
+
+// writeTAP prints a Test Anything Protocol point per file, failing those
+// in error, and one failing point per unused LICENSE entry, override or
+// ignore pattern.
+func writeTAP(w io.Writer, r Report) error {
+	lines := []string{`TAP version 13`, fmt.Sprintf("1..%d", len(r.Files)+len(r.ExtraLicenses)+len(r.Stale))}
+	n := 0
+	for _, res := range r.Files {
+		n++
+		var lics []string
+		for _, lic := range res.Licenses {
+			lics = append(lics, string(lic))
+		}
+		status := `ok`
+		if res.Error {
+			status = `not ok`
+		}
+		lines = append(lines, fmt.Sprintf("%s %d - %s (%s)", status, n, tapDescription.Replace(res.Path), tapDescription.Replace(withCodes(strings.Join(lics, ` `), res.Codes))))
+		if res.Error {
+			lines = append(lines, `  ---`, fmt.Sprintf("  line: %d", findingLine(res)), `  ...`)
+		}
+	}
+	for _, extra := range r.ExtraLicenses {
+		n++
+		lines = append(lines, fmt.Sprintf("not ok %d - LICENSE (No file matches @%s [WSL003])", n, tapDescription.Replace(extra)))
+	}
+	for _, st := range r.Stale {
+		n++
+		lines = append(lines, fmt.Sprintf("not ok %d - %s (%s)", n, tapDescription.Replace(st.Source), tapDescription.Replace(withCodes(staleFinding(st)+" "+st.Entry, []string{st.Code}))))
+	}
+	lines = append(lines, `# Repository license: `+r.Conclusion)
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// licenseLine is the line a license of a file is reported against: the
+// start of the phrase which identified it, or else 1.
+func licenseLine(res FileResult, lic License) int {
+	for _, ev := range res.Evidence {
+		if ev.License+`!` == lic && ev.StartLine > 0 {
+			return ev.StartLine
+		}
+	}
+	return 1
+}
+
+// documentedLine is the line of LICENSE bearing an `@`-line, or else 1.
+func documentedLine(pattern string) int {
+	b, err := readFile(`LICENSE`)
+	if err != nil {
+		return 1
+	}
+	for i, line := range strings.Split(string(b), "\n") {
+		if nfc(strings.TrimSpace(line)) == `@`+pattern {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+// writeCompact prints each finding as `path:line: error: message`, as
+// compilers do, for editors' quickfix lists and problem matchers.
+func writeCompact(w io.Writer, r Report) error {
+	var lines []string
+	for _, res := range r.Files {
+		if !res.Error {
+			continue
+		}
+		for _, lic := range res.Licenses {
+			if strings.HasSuffix(string(lic), `!`) {
+				lines = append(lines, fmt.Sprintf("%s:%d: error: %s", res.Path, licenseLine(res, lic), withCodes(licenseFinding(res.scanned, lic), []string{codeOf(lic)})))
+			}
+		}
+	}
+	for _, extra := range r.ExtraLicenses {
+		lines = append(lines, fmt.Sprintf("%s:%d: error: no file matches @%s [WSL003]", displayPath(`LICENSE`), documentedLine(extra), extra))
+	}
+	for _, st := range r.Stale {
+		file, line := staleLocation(st)
+		lines = append(lines, fmt.Sprintf("%s:%d: error: %s", displayPath(file), line, withCodes("nothing matches "+st.Entry, []string{st.Code})))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if maxErrors >= 0 && len(lines) > maxErrors {
+		lines = append(lines[:maxErrors], fmt.Sprintf("... and %d more errors.", len(lines)-maxErrors))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
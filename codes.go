@@ -0,0 +1,157 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// findingCode is the stable code of a kind of finding, with what causes
+// it and how to resolve it, as `weasel explain <code>` prints.
+type findingCode struct {
+	Code    string
+	Name    string
+	Summary string
+	Causes  string
+	Remedy  string
+}
+
+// findingCodes never change meaning; new kinds of finding take new codes.
+var findingCodes = []findingCode{
+	{`WSL001`, `undocumented`, `A file is under a license LICENSE does not document for it.`,
+		`The file's header, an inherited LICENSE file or an override gives it a license other than the project's, and no @-line of LICENSE describes the file. Where a .weasel.yml rule expects a license of the file, no other will do.`,
+		`Add an @-line describing the file to LICENSE, beneath the text of its license, or correct its header if the license is wrong.`},
+	{`WSL002`, `unknown`, `weasel could not identify the license of a file.`,
+		`The file has no header, or one weasel doesn't recognize, and no LICENSE file above it to inherit. Binary files and those in languages without comments often have none.`,
+		"Add a header, for which `weasel --fix` may help, or document the file's license with a line of .dependency_license, or ignore it. `weasel triage` does either interactively."},
+	{`WSL003`, `extra-license`, `An @-line of LICENSE describes no file.`,
+		`The files it described were removed or moved, or the pattern was mistyped. Only full scans report it, not weasel check.`,
+		`Remove the @-line from LICENSE, or correct its pattern.`},
+	{`WSL004`, `forbidden`, `A file's license may not be included in a project under the primary license.`,
+		"`weasel compat` found a license the compatibility matrix doesn't allow with the primary license, such as GPL code in an Apache project.",
+		`Remove or replace the file, or if it is in fact allowed, amend the primary license's entry in .license_compatibility.`},
+	{`WSL005`, `missing-header`, `A file lacks part of the header its .weasel.yml requires.`,
+		"The .weasel.yml governing the file sets `header`, `corporate-header` or `require`, and the file's header doesn't carry it.",
+		"Add the missing text to the file's header, for which `weasel --fix` may help."},
+	{`WSL006`, `unlisted-copyright`, `A file credits a copyright holder its .weasel.yml does not list.`,
+		"The copyright line of the file names none of the `copyright-holders` of its .weasel.yml, often because it was copied from elsewhere.",
+		"Credit a listed holder, or add the holder to `copyright-holders` if the code is theirs to contribute."},
+	{`WSL007`, `spdx-conflict`, `A file's SPDX-License-Identifier names a license its LICENSE file does not.`,
+		`The tag disagrees with the nearest LICENSE file, which usually means the header was pasted from another project.`,
+		`Correct the tag, or document the file's license as for WSL001.`},
+	{`WSL008`, `declared-mismatch`, `The license a project declares is not the one most of its headers carry.`,
+		`The LICENSE file of the project, or of a Go module within it, is under one license while most files' headers are under another.`,
+		`Correct the LICENSE file, or the headers.`},
+	{`WSL009`, `stale`, `An override or ignore pattern matches no file.`,
+		`The line of .dependency_license or ignore pattern of .weasel.yml no longer matches anything, since its files were removed or moved.`,
+		`Remove the line or pattern, or correct it.`},
+	{`WSL010`, `unreadable`, `A file could not be read, or took too long to identify.`,
+		"The file's permissions forbid reading it, it vanished during the scan, or identifying it exceeded --file-timeout.",
+		`Fix the file's permissions, ignore it, or raise --file-timeout.`},
+}
+
+// codeOf returns the code of a finding, such as `MIT!` or
+// `Stale-Override!`, or nothing for a license which is not one.
+func codeOf(finding License) string {
+	if !strings.HasSuffix(string(finding), `!`) {
+		return ``
+	}
+	base, _ := finding.split()
+	s := string(base)
+	switch {
+	case strings.HasPrefix(s, `Unknown`):
+		return `WSL002`
+	case s == `Extra-License`:
+		return `WSL003`
+	case strings.HasPrefix(s, `Incompatible-`):
+		return `WSL004`
+	case strings.HasPrefix(s, `Missing-`):
+		return `WSL005`
+	case s == `Unlisted-Copyright`:
+		return `WSL006`
+	case s == `SPDX-Conflict`:
+		return `WSL007`
+	case s == `Declared-Mismatch`:
+		return `WSL008`
+	case strings.HasPrefix(s, `Stale-`):
+		return `WSL009`
+	case s == `Timeout` || isReadError(s):
+		return `WSL010`
+	}
+	return `WSL001`
+}
+
+// codesOf returns the codes of the findings among a file's licenses, each
+// once, in order.
+func codesOf(lics []License) []string {
+	var codes []string
+	for _, lic := range lics {
+		if code := codeOf(lic); code != `` && !contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// withCodes appends the codes of the findings to a description of them.
+func withCodes(s string, codes []string) string {
+	if len(codes) == 0 {
+		return s
+	}
+	return s + ` [` + strings.Join(codes, `, `) + `]`
+}
+
+// lookupCode returns the finding of a code, case aside.
+func lookupCode(code string) (findingCode, bool) {
+	for _, c := range findingCodes {
+		if strings.EqualFold(c.Code, code) {
+			return c, true
+		}
+	}
+	return findingCode{}, false
+}
+
+// isCode tells whether an operand is meant as a finding code rather than
+// a file.
+func isCode(s string) bool {
+	if len(s) != 6 || !strings.EqualFold(s[:3], `WSL`) {
+		return false
+	}
+	for _, c := range s[3:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// explainCode prints what a finding code means, and how to resolve it. It
+// returns the exit status.
+func explainCode(w io.Writer, code string) int {
+	c, ok := lookupCode(code)
+	if !ok {
+		fmt.Fprintln(w, "Unknown finding code: `"+code+"`!")
+		return 1
+	}
+	fmt.Fprintf(w, "%s %s: %s\n\n", c.Code, c.Name, c.Summary)
+	fmt.Fprintln(w, "Causes: "+c.Causes)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Remediation: "+c.Remedy)
+	return 0
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// textColumns are the columns of the text report, in order.
+var textColumns = []string{`status`, `licenses`, `path`}
+
+// columnWidths are the least widths of the columns, to which they keep
+// unless something longer is listed.
+var columnWidths = map[string]int{
+	`status`:   5,
+	`licenses`: 40,
+}
+
+// rightAligned are the columns which are aligned to the right.
+var rightAligned = map[string]bool{
+	`licenses`:   true,
+	`confidence`: true,
+	`size`:       true,
+}
+
+// parseColumns reads the comma separated columns of --columns.
+func parseColumns(s string) ([]string, bool) {
+	var cols []string
+	for _, col := range strings.Split(s, `,`) {
+		col = strings.TrimSpace(col)
+		if col != `status` && col != `licenses` && col != `confidence` && col != `path` && col != `size` {
+			return nil, false
+		}
+		cols = append(cols, col)
+	}
+	return cols, true
+}
+
+// showsColumn tells whether the text report has the column.
+func showsColumn(col string) bool {
+	for _, c := range textColumns {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// textRow is a row of the text report, and what is printed beneath it.
+type textRow struct {
+	cells map[string]string
+	below string
+}
+
+// fileRow is the row of a file, printed at path: the sizes and confidences
+// of other rows are left empty.
+func fileRow(status, licenses, path, name string) textRow {
+	cells := map[string]string{`status`: status, `licenses`: licenses, `path`: path}
+	if showsColumn(`confidence`) {
+		cells[`confidence`] = confidence(name)
+	}
+	if showsColumn(`size`) {
+		cells[`size`] = `-`
+		if fi, err := os.Stat(name); err == nil && fi.Mode().IsRegular() {
+			cells[`size`] = strconv.FormatInt(fi.Size(), 10)
+		}
+	}
+	return textRow{cells: cells}
+}
+
+// confidence is how sure weasel is of the least certain license of a file:
+// 100% for a matching phrase, or the share of the reference text found for
+// a fuzzy match. It is `-` for files no license was identified in.
+func confidence(name string) string {
+	pct := -1
+	for _, ev := range evidenceFor(name) {
+		n := 100
+		var found int
+		if _, err := fmt.Sscanf(ev.Phrase, "%d%% of the reference text", &found); err == nil {
+			n = found
+		}
+		if pct < 0 || n < pct {
+			pct = n
+		}
+	}
+	if pct < 0 {
+		return `-`
+	}
+	return strconv.Itoa(pct) + `%`
+}
+
+// widths fits each column to the longest of its cells.
+func widths(rows []textRow) map[string]int {
+	w := make(map[string]int)
+	for _, col := range textColumns {
+		w[col] = columnWidths[col]
+	}
+	for _, row := range rows {
+		for col, cell := range row.cells {
+			if len(cell) > w[col] {
+				w[col] = len(cell)
+			}
+		}
+	}
+	return w
+}
+
+// writeRow prints a row with the columns padded to the widths, leaving the
+// last unpadded.
+func writeRow(w io.Writer, row textRow, widths map[string]int) error {
+	cells := make([]string, len(textColumns))
+	for i, col := range textColumns {
+		switch {
+		case rightAligned[col]:
+			cells[i] = fmt.Sprintf("%*s", widths[col], row.cells[col])
+		case i < len(textColumns)-1:
+			cells[i] = fmt.Sprintf("%-*s", widths[col], row.cells[col])
+		default:
+			cells[i] = row.cells[col]
+		}
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(cells, ` `)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, row.below)
+	return err
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commentMarkers open, close or continue a comment at the edges of a line.
+// Longer markers come first, so `<!--` is not taken for `<`.
+var commentMarkers = []string{
+	`<!--`, `-->`, `"""`, `'''`, `/*`, `*/`, `//`, `--`, `#`, `;`, `*`,
+}
+
+// commentText strips the comment markers, gutters and banner decoration
+// from the edges of a line, leaving the text of the comment.
+func commentText(line string) string {
+	for {
+		trimmed := strings.TrimSpace(line)
+		for _, marker := range commentMarkers {
+			trimmed = strings.TrimPrefix(trimmed, marker)
+			trimmed = strings.TrimSuffix(trimmed, marker)
+		}
+		if trimmed == line {
+			break
+		}
+		line = trimmed
+	}
+	/* A banner line such as `#######` or `//=====` holds no words. */
+	if strings.IndexFunc(line, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) < 0 {
+		return ``
+	}
+	return line
+}
+
+// readWords passes the normalized words of in, with comment markers
+// removed, and the numbers of the lines bearing them to word until it
+// returns false. It returns the error of reading in, if any.
+func readWords(in io.Reader, word func(w string, lineNum int) bool) error {
+	r := bufio.NewReaderSize(in, 64*1024)
+	continued, tagged := false, false
+	partial := ``
+	lineNum := 0
+	for {
+		b, isPrefix, err := r.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line := string(b)
+		/* Only whole lines, or the start of long ones, bear markers. */
+		if !continued {
+			line = commentText(line)
+			lineNum++
+		}
+		continued = isPrefix
+		/* A long line is read in parts, and a word may run on into the next. */
+		line, partial = partial+line, ``
+		if isPrefix {
+			if i := strings.LastIndexFunc(line, unicode.IsSpace); i < 0 {
+				line, partial = ``, line
+			} else {
+				_, n := utf8.DecodeRuneInString(line[i:])
+				line, partial = line[:i+n], line[i+n:]
+			}
+		}
+		for _, raw := range strings.Fields(line) {
+			w := strings.ToLower(stripPunc(raw))
+			if tagged {
+				w = spdxIDWord(raw)
+			}
+			if len(w) == 0 {
+				continue
+			}
+			if !word(w, lineNum) {
+				return nil
+			}
+			tagged = w == spdxTagWord
+		}
+	}
+}
+
+// debugTokens prints the words of a file as the matchers see them, a line
+// of the file to a line of output.
+func debugTokens(w io.Writer, name string) error {
+	f, err := openSource(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	last := 0
+	err = readWords(f, func(word string, lineNum int) bool {
+		if lineNum != last {
+			if last != 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%d:", lineNum)
+			last = lineNum
+		}
+		fmt.Fprint(w, ` `+word)
+		return true
+	})
+	if last != 0 {
+		fmt.Fprintln(w)
+	}
+	return err
+}
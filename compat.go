@@ -0,0 +1,133 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+var primaryLicense License
+
+// compatible lists, for each primary license, the licenses which may be
+// included in a project released under it. Fonts under the OFL may be
+// bundled with any software. It may be amended by a
+// .license_compatibility file in the root of the project. Licenses are
+// named as the matchers name them, to which SPDX identifiers are mapped
+// by spdxName.
+var compatible = map[License][]License{
+	`Apache`:   {`Apache`, `BSD`, `GoBSD`, `MIT`, `ISC`, `X11`, `WTFPL`, `OFL`},
+	`MIT`:      {`MIT`, `BSD`, `GoBSD`, `ISC`, `X11`, `WTFPL`, `OFL`},
+	`BSD`:      {`BSD`, `GoBSD`, `MIT`, `ISC`, `X11`, `WTFPL`, `OFL`},
+	`GPL/LGPL`: {`GPL/LGPL`, `BSD`, `GoBSD`, `MIT`, `ISC`, `X11`, `WTFPL`, `OFL`},
+}
+
+func loadCompatibility(compatFile string) {
+	f, err := os.Open(compatFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == `` || line[0] == '#' {
+			continue
+		}
+
+		parts := strings.SplitN(line, `:`, 2)
+		if len(parts) < 2 {
+			panic("Malformed line in " + compatFile + ": " + line)
+		}
+
+		var lics []License
+		for _, lic := range strings.Split(parts[1], `,`) {
+			if lic = strings.TrimSpace(lic); lic != `` {
+				lics = append(lics, spdxName(lic))
+			}
+		}
+		compatible[spdxName(strings.TrimSpace(parts[0]))] = lics
+	}
+}
+
+// compat reports every file bearing a license which may not be included in
+// a project under the primary license, returning true if any were found.
+func compat(w io.Writer, files map[string][]License) bool {
+	loadCompatibility(`.license_compatibility`)
+
+	primary := primaryLicense
+	if primary == `` {
+		var declared []License
+		for _, lic := range files[`LICENSE`] {
+			if base, _ := lic.split(); base != `Docs` {
+				declared = append(declared, base)
+			}
+		}
+		if len(declared) != 1 {
+			fmt.Fprintln(w, "Cannot determine the primary license from LICENSE, use --primary!")
+			return true
+		}
+		primary = declared[0]
+	}
+
+	allowed, ok := compatible[spdxName(string(primary))]
+	if !ok {
+		fmt.Fprintln(w, "No compatibility rules for primary license: "+string(primary)+"!")
+		return true
+	}
+
+	var filenames []string
+	for filename := range files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var rows []textRow
+	for _, filename := range filenames {
+		lics := files[filename]
+		if Has(lics, License(`Ignore`)) {
+			continue
+		}
+		for _, lic := range lics {
+			base, _ := lic.split()
+			base = spdxName(string(base))
+			if _, ok := notLicenses[base]; ok {
+				continue
+			}
+			if strings.HasPrefix(string(base), `Unknown`) || strings.HasPrefix(string(base), `Error`) || duplicate(base) {
+				continue
+			}
+			if Has(allowed, base) {
+				continue
+			}
+			if useSPDX {
+				base = base.SPDX()
+			}
+			rows = append(rows, textRow{cells: map[string]string{`status`: "Error", `licenses`: withCodes("Incompatible-"+string(base)+"!", []string{`WSL004`}), `path`: filename}})
+		}
+	}
+	fit := widths(rows)
+	for _, row := range rows {
+		writeRow(w, row, fit)
+	}
+	return len(rows) != 0
+}
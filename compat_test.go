@@ -0,0 +1,80 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompatPrimary(t *testing.T) {
+	files := map[string][]License{
+		`LICENSE`: {`Apache`},
+		`a.go`:    {`Apache`},
+		`b.go`:    {`MIT`},
+		`c.go`:    {`GPL-2.0-only`},
+		`d.go`:    {`BSD-3-Clause`},
+	}
+	tests := []struct {
+		name    string
+		primary License
+		compat  string
+		want    []string
+	}{
+		{`informal name`, `Apache`, ``, []string{`c.go`}},
+		{`SPDX identifier`, `Apache-2.0`, ``, []string{`c.go`}},
+		{`SPDX identifier of BSD`, `BSD-3-Clause`, ``, []string{`LICENSE`, `a.go`, `c.go`}},
+		{`from LICENSE`, ``, ``, []string{`c.go`}},
+		{`rules by SPDX identifier`, `Apache`, "Apache-2.0: Apache-2.0, GPL-2.0-only, BSD-3-Clause\n", []string{`b.go`}},
+		{`rules by informal name`, `Apache-2.0`, "Apache: Apache, GPL-2.0-only, BSD\n", []string{`b.go`}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tree := map[string]string{}
+			if tc.compat != `` {
+				tree[`.license_compatibility`] = tc.compat
+			}
+			inTree(t, tree)
+			defer func(rules map[License][]License) { compatible = rules }(copyRules(compatible))
+			defer func(primary License) { primaryLicense = primary }(primaryLicense)
+			primaryLicense = tc.primary
+
+			var out bytes.Buffer
+			failed := compat(&out, files)
+			if failed != (len(tc.want) != 0) {
+				t.Errorf("compat failed = %v, with output %q", failed, out.String())
+			}
+			lines := bytes.Split(bytes.TrimSuffix(out.Bytes(), []byte("\n")), []byte("\n"))
+			if len(lines) != len(tc.want) {
+				t.Fatalf("compat printed %q, want errors of %v", out.String(), tc.want)
+			}
+			for i, name := range tc.want {
+				if !bytes.HasSuffix(lines[i], []byte(`[WSL004] `+name)) {
+					t.Errorf("line %d is %q, want the error of %s", i, lines[i], name)
+				}
+			}
+		})
+	}
+}
+
+func copyRules(rules map[License][]License) map[License][]License {
+	copied := make(map[License][]License, len(rules))
+	for primary, lics := range rules {
+		copied[primary] = lics
+	}
+	return copied
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+)
+
+// headerComponents are the elements of a header a .weasel.yml may
+// `require`, with the finding reported for a file lacking each.
+var headerComponents = map[string]License{
+	`license`:   `Missing-License-Notice!`,
+	`copyright`: `Missing-Copyright!`,
+	`spdx`:      `Missing-SPDX-Tag!`,
+}
+
+// componentExempt are the kinds of file not checked for header components.
+// Other findings, such as Missing-Header, are no reason not to check them.
+var componentExempt = map[License]struct{}{
+	`Ignore`:    {},
+	`Docs`:      {},
+	`Empty`:     {},
+	`Generated`: {},
+	`Vendored`:  {},
+	`Fixture`:   {},
+}
+
+// markMissingComponents checks each licensed file for every header
+// component its config requires, reporting each one missing on its own.
+// License texts, vendored files and files documented in
+// .dependency_license are exempt, as are files weasel does not read as
+// licensed, and the componentExempt kinds.
+func markMissingComponents(files map[string][]License) {
+	for name, lics := range files {
+		cfg := configFor(name)
+		if cfg == nil || len(cfg.Require) == 0 || licenseLike(name) || Vendored(name) || len(override[name]) != 0 {
+			continue
+		}
+		licensed, notice := false, false
+		for _, lic := range lics {
+			base, suffix := lic.split()
+			if _, ok := componentExempt[base]; ok {
+				licensed = false
+				break
+			}
+			if countable(base) {
+				licensed = true
+				notice = notice || !strings.Contains(suffix, `~`)
+			}
+		}
+		if !licensed {
+			continue
+		}
+		for _, component := range cfg.Require {
+			var present bool
+			switch component {
+			case `license`:
+				present = notice
+			case `copyright`:
+				present = len(copyrightLines(name)) != 0
+			case `spdx`:
+				spdxTags.Lock()
+				present = len(spdxTags.byName[name]) != 0
+				spdxTags.Unlock()
+			}
+			if !present {
+				files[name] = append(files[name], headerComponents[component])
+			}
+		}
+	}
+}
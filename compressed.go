@@ -0,0 +1,92 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// compressedReader reads the decompressed text of a file, closing the file
+// and anything decompressing it on Close.
+type compressedReader struct {
+	io.Reader
+	close func() error
+}
+
+func (r compressedReader) Close() error {
+	return r.close()
+}
+
+// isArchive reports whether a compressed file holds several files, rather
+// than a single compressed text.
+func isArchive(lower string) bool {
+	return strings.HasSuffix(lower, `.tgz`) || strings.Contains(lower, `.tar.`)
+}
+
+// decompress returns the decompressed text of a `.gz`, `.bz2` or `.xz`
+// file, or the file as it stands if it is not compressed, or cannot be
+// decompressed. xz streams are decompressed by the `xz` tool, when it is
+// installed. The file may be one of the worktree or a blob of history.
+func decompress(name string, f io.ReadSeekCloser) (io.ReadCloser, error) {
+	lower := strings.ToLower(name)
+	if isArchive(lower) {
+		return f, nil
+	}
+	switch {
+	case strings.HasSuffix(lower, `.gz`):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			/* Not gzip after all, so read it as it is. */
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				f.Close()
+				return nil, err
+			}
+			return f, nil
+		}
+		return compressedReader{zr, func() error {
+			zr.Close()
+			return f.Close()
+		}}, nil
+	case strings.HasSuffix(lower, `.bz2`):
+		return compressedReader{bzip2.NewReader(f), f.Close}, nil
+	case strings.HasSuffix(lower, `.xz`):
+		if _, err := exec.LookPath(`xz`); err != nil {
+			return f, nil
+		}
+		cmd := exec.Command(`xz`, `-dc`)
+		cmd.Stdin = f
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return compressedReader{out, func() error {
+			out.Close()
+			cmd.Wait()
+			return f.Close()
+		}}, nil
+	}
+	return f, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+)
+
+var printConclusion bool
+
+// notLicenses are the names weasel reports which describe a file rather than
+// license it, and so take no part in the repository's conclusion.
+var notLicenses = map[License]struct{}{
+	`Docs`:                     {},
+	`Empty`:                    {},
+	`Ignore`:                   {},
+	`Generated`:                {},
+	`Vendored`:                 {},
+	`Fixture`:                  {},
+	`Missing-Header`:           {},
+	`Missing-Corporate-Header`: {},
+	`Unlisted-Copyright`:       {},
+	`Missing-License-Notice`:   {},
+	`Missing-Copyright`:        {},
+	`Missing-SPDX-Tag`:         {},
+	`Timeout`:                  {},
+	`Declared-Mismatch`:        {},
+	`SPDX-Conflict`:            {},
+	`Baselined`:                {},
+}
+
+// Conclude combines the licenses of every file into a single expression,
+// such as `Apache AND MIT`, describing the repository as a whole. Ignored
+// files, test fixtures, unidentified files and errors are left out.
+func Conclude(files map[string][]License) string {
+	var all []License
+	for _, lics := range files {
+		if Has(lics, License(`Ignore`)) || Has(lics, License(`Fixture`)) {
+			continue
+		}
+		for _, lic := range lics {
+			base, _ := lic.split()
+			if _, ok := notLicenses[base]; ok {
+				continue
+			}
+			if strings.HasPrefix(string(base), `Unknown`) || strings.HasPrefix(string(base), `Error`) || duplicate(base) {
+				continue
+			}
+			if useSPDX {
+				base = base.SPDX()
+			}
+			all = append(all, base)
+		}
+	}
+
+	var parts []string
+	for _, lic := range Uniq(all) {
+		parts = append(parts, string(lic))
+	}
+	return strings.Join(parts, ` AND `)
+}
@@ -0,0 +1,435 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const configName = `.weasel.yml`
+
+// Config is a .weasel.yml file, which governs the files beneath the
+// directory containing it, up to any .weasel.yml nested further down.
+type Config struct {
+	Dir     string  /* Slash-separated, `.` at the root. */
+	License License /* The license files need not document, Apache if unset. */
+	Header  string  /* Text which every licensed file must contain. */
+	Ignore  []string
+	Rules   []licenseRule /* Licenses expected of particular paths instead. */
+	Holders []string      /* The copyright holders files may credit, any if empty. */
+
+	/* Company text which every licensed file must contain besides Header. */
+	CorporateHeader string
+	Require         []string /* The headerComponents every licensed file must have. */
+
+	/* The names to report licenses by instead of those weasel gives them. */
+	Aliases map[License]License
+
+	/* Test fixture directories, whose files are reported but never fail. */
+	Fixtures []string
+
+	/* Custom licenses, by name, with the paths of their reference texts. */
+	Licenses map[License]string
+	TextDir  string /* The directory the paths of reference texts are relative to. */
+	File     string /* The config file itself. */
+	User     bool   /* The user's weasel.yml, which is outside any tree scanned. */
+}
+
+// licenseRule expects a license of the files a pattern matches, written
+// `docs/** => CC-BY-4.0`.
+type licenseRule struct {
+	Pattern string
+	License License
+}
+
+// parseRule splits a rule into its pattern and license.
+func parseRule(s string) (licenseRule, bool) {
+	parts := strings.SplitN(s, `=>`, 2)
+	if len(parts) != 2 {
+		return licenseRule{}, false
+	}
+	pattern, lic := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if pattern == `` || lic == `` {
+		return licenseRule{}, false
+	}
+	return licenseRule{pattern, License(lic)}, true
+}
+
+var configs = struct {
+	sync.Mutex
+	byDir map[string]*Config /* nil where a directory has no .weasel.yml. */
+
+	user       *Config
+	userLoaded bool
+}{byDir: make(map[string]*Config)}
+
+// configFor returns the config of the nearest directory above name with a
+// .weasel.yml, or else the user's weasel.yml, or nil. Configs are
+// discovered as the walk reaches them.
+func configFor(name string) *Config {
+	configs.Lock()
+	defer configs.Unlock()
+
+	dir := path.Dir(filepath.ToSlash(name))
+	for {
+		cfg, ok := configs.byDir[dir]
+		if !ok {
+			cfg = loadConfig(dir)
+			configs.byDir[dir] = cfg
+		}
+		if cfg != nil {
+			return cfg
+		}
+		if dir == `.` || dir == `/` {
+			return userConfig()
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// userConfig returns the user's weasel.yml, which governs the files of a
+// project as if it were at the project's root. configs must be locked.
+func userConfig() *Config {
+	if !configs.userLoaded {
+		configs.userLoaded = true
+		if dir, err := configDir(); err == nil {
+			configs.user = readConfig(`.`, filepath.Join(dir, userConfigName), ioutil.ReadFile)
+			if configs.user != nil {
+				configs.user.User = true
+			}
+		}
+	}
+	return configs.user
+}
+
+func loadConfig(dir string) *Config {
+	return readConfig(dir, filepath.Join(filepath.FromSlash(dir), configName), readFile)
+}
+
+func readConfig(dir, configFile string, read func(string) ([]byte, error)) *Config {
+	b, err := read(configFile)
+	if err != nil {
+		return nil
+	}
+	cfg, err := parseConfig(dir, string(b))
+	if err != nil {
+		panic("Malformed " + configFile + ": " + err.Error())
+	}
+	cfg.File = configFile
+	cfg.TextDir = filepath.Dir(configFile)
+	return cfg
+}
+
+func parseConfig(dir, doc string) (*Config, error) {
+	root, err := parseYAML(doc)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{
+		Dir:             dir,
+		License:         License(root.Get(`license`).Strings0()),
+		Header:          root.Get(`header`).Strings0(),
+		CorporateHeader: root.Get(`corporate-header`).Strings0(),
+		Ignore:          root.Get(`ignore`).Strings(),
+		Holders:         root.Get(`copyright-holders`).Strings(),
+		Require:         root.Get(`require`).Strings(),
+		Fixtures:        root.Get(`fixtures`).Strings(),
+	}
+	for _, component := range cfg.Require {
+		if _, ok := headerComponents[component]; !ok {
+			return nil, &yamlError{root.Get(`require`).Line, "unknown header component `" + component + "`"}
+		}
+	}
+	if rules := root.Get(`rules`); rules != nil {
+		items := rules.List
+		if rules.List == nil && !rules.IsMap {
+			items = []*yamlNode{rules}
+		}
+		if rules.IsMap {
+			return nil, &yamlError{rules.Line, "rules must be a list of `<pattern> => <license>`"}
+		}
+		for _, item := range items {
+			rule, ok := parseRule(item.Value)
+			if !ok {
+				return nil, &yamlError{item.Line, "rules must be a list of `<pattern> => <license>`"}
+			}
+			cfg.Rules = append(cfg.Rules, rule)
+		}
+	}
+	if aliases := root.Get(`aliases`); aliases != nil {
+		items := aliases.List
+		if aliases.List == nil && !aliases.IsMap {
+			items = []*yamlNode{aliases}
+		}
+		if aliases.IsMap {
+			return nil, &yamlError{aliases.Line, "aliases must be a list of `<license> => <name>`"}
+		}
+		cfg.Aliases = make(map[License]License)
+		for _, item := range items {
+			alias, ok := parseRule(item.Value)
+			if !ok {
+				return nil, &yamlError{item.Line, "aliases must be a list of `<license> => <name>`"}
+			}
+			cfg.Aliases[License(alias.Pattern)] = alias.License
+		}
+	}
+	if licenses := root.Get(`licenses`); licenses != nil {
+		if !licenses.IsMap {
+			return nil, &yamlError{licenses.Line, "licenses must map names to reference texts"}
+		}
+		cfg.Licenses = make(map[License]string)
+		for _, name := range licenses.Keys {
+			text := licenses.Map[name]
+			if text.Value == `` {
+				return nil, &yamlError{text.Line, "licenses must map names to reference texts"}
+			}
+			cfg.Licenses[License(name)] = text.Value
+		}
+	}
+	return cfg, nil
+}
+
+// rel returns name relative to the config's directory, slash-separated.
+func (c *Config) rel(name string) string {
+	name = nfc(filepath.ToSlash(name))
+	if c.Dir == `.` {
+		return strings.TrimPrefix(name, `./`)
+	}
+	return strings.TrimPrefix(name, c.Dir+`/`)
+}
+
+// textFile is the path of the reference text of a custom license.
+func (c *Config) textFile(lic License) string {
+	return filepath.Join(c.TextDir, filepath.FromSlash(c.Licenses[lic]))
+}
+
+// readText reads the reference text of a custom license, from the tree
+// scanned unless the config is the user's.
+func (c *Config) readText(lic License) ([]byte, error) {
+	if c.User {
+		return ioutil.ReadFile(c.textFile(lic))
+	}
+	return readFile(c.textFile(lic))
+}
+
+// Ignores reports whether an `ignore` pattern matches the file or
+// directory.
+func (c *Config) Ignores(name string) bool {
+	return c.ignoring(name) != ``
+}
+
+// ignoring returns the first `ignore` pattern matching the file or
+// directory, if any.
+func (c *Config) ignoring(name string) string {
+	rel := c.rel(name)
+	for _, pattern := range c.Ignore {
+		if matchGlob(pattern, rel) {
+			return pattern
+		}
+	}
+	return ``
+}
+
+// ruleFor returns the license a rule of the nearest config expects of a
+// file, the first rule to match winning.
+func ruleFor(name string) (License, bool) {
+	cfg := configFor(name)
+	if cfg == nil {
+		return ``, false
+	}
+	rel := cfg.rel(name)
+	for _, rule := range cfg.Rules {
+		if matchGlob(rule.Pattern, rel) {
+			return rule.License, true
+		}
+	}
+	return ``, false
+}
+
+// expectedLicense is the license which files need not document: that of
+// a rule matching the file, or else the `license` of its config.
+func expectedLicense(name string) License {
+	if lic, ok := ruleFor(name); ok {
+		return canonical(name, lic)
+	}
+	if cfg := configFor(name); cfg != nil && cfg.License != `` {
+		return canonical(name, cfg.License)
+	}
+	return canonical(name, License(`Apache`))
+}
+
+// canonical is the name the nearest config's `aliases` give a license,
+// keeping its markers, or the license itself.
+func canonical(name string, lic License) License {
+	cfg := configFor(name)
+	if cfg == nil || cfg.Aliases == nil {
+		return lic
+	}
+	base, suffix := lic.split()
+	if alias, ok := cfg.Aliases[base]; ok {
+		return License(string(alias) + suffix)
+	}
+	return lic
+}
+
+// canonicalAll renames each of the licenses of a file by the aliases of its
+// config, which may leave two the same.
+func canonicalAll(name string, lics []License) []License {
+	for i, lic := range lics {
+		lics[i] = canonical(name, lic)
+	}
+	return Uniq(lics)
+}
+
+// matchGlob matches a slash-separated path against a pattern in the syntax
+// of path.Match, extended with `**` for any number of directories. Patterns
+// without a `/` match the final element of the path at any depth.
+func matchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(nfc(pattern), `/`)
+	if !strings.Contains(pattern, `/`) {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, `/`), strings.Split(name, `/`))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == `**` {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); !ok || err != nil {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// hasPhrase reports whether the file contains the words of phrase, compared
+// as the license matchers compare them.
+func hasPhrase(name, phrase string) bool {
+	var words []string
+	for _, word := range makeWords(phrase) {
+		if word != `` {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return true
+	}
+
+	f, err := openSource(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	i, found := 0, false
+	readWords(f, func(word string, _ int) bool {
+		if words[i] == word {
+			i++
+		} else if words[0] == word {
+			i = 1
+		} else {
+			i = 0
+		}
+		found = i == len(words)
+		return !found
+	})
+	return found
+}
+
+// missingHeader reports whether a licensed file lacks the header its config
+// requires.
+func missingHeader(name string, licenses []License) bool {
+	return lacksRequired(name, licenses, func(cfg *Config) string { return cfg.Header })
+}
+
+// missingCorporateHeader reports whether a licensed file lacks the company
+// header its config requires besides the license header.
+func missingCorporateHeader(name string, licenses []License) bool {
+	return lacksRequired(name, licenses, func(cfg *Config) string { return cfg.CorporateHeader })
+}
+
+// lacksRequired reports whether a licensed file lacks text its config
+// requires, which is nothing when the text is empty.
+func lacksRequired(name string, licenses []License, required func(*Config) string) bool {
+	cfg := configFor(name)
+	if cfg == nil || required(cfg) == `` || len(licenses) == 0 {
+		return false
+	}
+	for _, lics := range [][]License{licenses, override[name]} {
+		for _, lic := range lics {
+			if _, ok := notLicenses[lic]; ok {
+				return false
+			}
+		}
+	}
+	return !hasPhrase(name, required(cfg))
+}
+
+// unlistedCopyright reports whether a licensed file credits a copyright
+// holder other than those its config lists. License texts, vendored files
+// and files documented in LICENSE are expected to credit others.
+func unlistedCopyright(name string, licenses []License) bool {
+	cfg := configFor(name)
+	if cfg == nil || len(cfg.Holders) == 0 || len(licenses) == 0 {
+		return false
+	}
+	if licenseLike(name) || Vendored(name) || documented.documenting(name) != `` {
+		return false
+	}
+	for _, lics := range [][]License{licenses, override[name]} {
+		for _, lic := range lics {
+			if _, ok := notLicenses[lic]; ok {
+				return false
+			}
+		}
+	}
+	for _, line := range copyrightLines(name) {
+		if !creditsAny(line, cfg.Holders) {
+			return true
+		}
+	}
+	return false
+}
+
+// creditsAny reports whether a copyright line names any of the holders,
+// compared word by word, ignoring case and punctuation.
+func creditsAny(line string, holders []string) bool {
+	text := ` ` + strings.Join(strings.Fields(strings.Join(makeWords(line), ` `)), ` `) + ` `
+	for _, holder := range holders {
+		words := strings.Fields(strings.Join(makeWords(holder), ` `))
+		if len(words) > 0 && strings.Contains(text, ` `+strings.Join(words, ` `)+` `) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// configKeys are the keys a .weasel.yml may have.
+var configKeys = map[string]bool{`license`: true, `header`: true, `corporate-header`: true, `ignore`: true, `licenses`: true, `rules`: true, `copyright-holders`: true, `require`: true, `aliases`: true, `fixtures`: true}
+
+// knownLicenses returns every license name weasel can identify, other than
+// the custom licenses of a project.
+func knownLicenses() map[License]bool {
+	known := make(map[License]bool)
+	for _, p := range builtinPatterns {
+		known[p.license] = true
+	}
+	return known
+}
+
+// validateConfigs checks every .weasel.yml beneath the current directory,
+// printing what is wrong with each. It returns the exit status.
+func validateConfigs(w io.Writer) int {
+	var names []string
+	filepath.Walk(`.`, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && info.Name() == `.git` {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && info.Name() == configName {
+			names = append(names, name)
+		}
+		return nil
+	})
+
+	/* The licenses of the root config, or else the user's, may be named by any config. */
+	known := knownLicenses()
+	rootConfig := configName
+	if _, err := os.Stat(configName); err != nil {
+		if dir, err := configDir(); err == nil {
+			rootConfig = filepath.Join(dir, userConfigName)
+		}
+	}
+	if b, err := ioutil.ReadFile(rootConfig); err == nil {
+		if root, err := parseYAML(string(b)); err == nil && root.Get(`licenses`) != nil {
+			for _, name := range root.Get(`licenses`).Keys {
+				known[License(name)] = true
+			}
+		}
+	}
+
+	code := 0
+	for _, name := range names {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(w, name+": "+err.Error())
+			code = 1
+			continue
+		}
+		dir := filepath.ToSlash(filepath.Dir(name))
+		for _, err := range validateConfig(dir, filepath.Dir(name), string(b), known) {
+			fmt.Fprintln(w, name+": "+err.Error())
+			code = 1
+		}
+	}
+
+	/* The user's config applies as if at the root of the project. */
+	if dir, err := configDir(); err == nil {
+		name := filepath.Join(dir, userConfigName)
+		if b, err := ioutil.ReadFile(name); err == nil {
+			for _, err := range validateConfig(`.`, dir, string(b), known) {
+				fmt.Fprintln(w, name+": "+err.Error())
+				code = 1
+			}
+		}
+	}
+	return code
+}
+
+// validateConfig checks a .weasel.yml in dir for unknown keys, values of
+// the wrong kind, malformed or repeated ignore patterns, malformed rules,
+// aliases, copyright holders and header components, undefined license
+// names and unreadable reference texts, which are relative to textDir.
+func validateConfig(dir, textDir, doc string, known map[License]bool) []*yamlError {
+	root, err := parseYAML(doc)
+	if err != nil {
+		if yerr, ok := err.(*yamlError); ok {
+			return []*yamlError{yerr}
+		}
+		return []*yamlError{{1, err.Error()}}
+	}
+	if !root.IsMap {
+		return []*yamlError{{root.Line, "expected a mapping of settings"}}
+	}
+
+	var errs []*yamlError
+	for i, key := range root.Keys {
+		if !configKeys[key] {
+			errs = append(errs, &yamlError{root.KeyLines[i], "unknown key `" + key + "`"})
+		}
+	}
+
+	if n := root.Get(`aliases`); n != nil {
+		if n.IsMap {
+			errs = append(errs, &yamlError{root.KeyLine(`aliases`), "`aliases` must be a list of `<license> => <name>`"})
+		}
+		items := n.List
+		if n.List == nil && !n.IsMap && n.Value != `` {
+			items = []*yamlNode{n}
+		}
+		/* The license and rules of this config may name licenses by their aliases. */
+		aliased := make(map[License]bool)
+		seen := make(map[License]int)
+		for _, item := range items {
+			alias, ok := parseRule(item.Value)
+			if item.IsMap || item.List != nil || !ok {
+				errs = append(errs, &yamlError{item.Line, "aliases must be written `<license> => <name>`"})
+				continue
+			}
+			lic := License(alias.Pattern)
+			if !known[lic] {
+				errs = append(errs, &yamlError{item.Line, "undefined license `" + alias.Pattern + "`"})
+			}
+			if line, dup := seen[lic]; dup {
+				errs = append(errs, &yamlError{item.Line, fmt.Sprintf("alias of `%s` repeats line %d", alias.Pattern, line)})
+			}
+			seen[lic] = item.Line
+			aliased[alias.License] = true
+		}
+		if len(aliased) > 0 {
+			all := make(map[License]bool)
+			for lic := range known {
+				all[lic] = true
+			}
+			for lic := range aliased {
+				all[lic] = true
+			}
+			known = all
+		}
+	}
+
+	for _, key := range []string{`license`, `header`, `corporate-header`} {
+		if n := root.Get(key); n != nil && (n.IsMap || n.List != nil) {
+			errs = append(errs, &yamlError{n.Line, "`" + key + "` must be a single value"})
+		}
+	}
+	if n := root.Get(`license`); n != nil && n.Value != `` && !known[License(n.Value)] {
+		errs = append(errs, &yamlError{n.Line, "undefined license `" + n.Value + "`"})
+	}
+
+	for _, key := range []string{`ignore`, `fixtures`} {
+		n := root.Get(key)
+		if n == nil {
+			continue
+		}
+		if n.IsMap {
+			errs = append(errs, &yamlError{root.KeyLine(key), "`" + key + "` must be a list of patterns"})
+		}
+		items := n.List
+		if n.List == nil && !n.IsMap && n.Value != `` {
+			items = []*yamlNode{n}
+		}
+		seen := make(map[string]int)
+		for _, item := range items {
+			if item.IsMap || item.List != nil {
+				errs = append(errs, &yamlError{item.Line, key + " patterns must be single values"})
+				continue
+			}
+			if err := checkGlob(item.Value); err != `` {
+				errs = append(errs, &yamlError{item.Line, "bad pattern `" + item.Value + "`: " + err})
+			}
+			if line, dup := seen[item.Value]; dup {
+				errs = append(errs, &yamlError{item.Line, fmt.Sprintf("pattern `%s` repeats line %d", item.Value, line)})
+			}
+			seen[item.Value] = item.Line
+		}
+	}
+
+	if n := root.Get(`copyright-holders`); n != nil {
+		if n.IsMap {
+			errs = append(errs, &yamlError{root.KeyLine(`copyright-holders`), "`copyright-holders` must be a list of names"})
+		}
+		items := n.List
+		if n.List == nil && !n.IsMap && n.Value != `` {
+			items = []*yamlNode{n}
+		}
+		for _, item := range items {
+			if item.IsMap || item.List != nil || item.Value == `` {
+				errs = append(errs, &yamlError{item.Line, "copyright holders must be single values"})
+			}
+		}
+	}
+
+	if n := root.Get(`require`); n != nil {
+		if n.IsMap {
+			errs = append(errs, &yamlError{root.KeyLine(`require`), "`require` must be a list of header components"})
+		}
+		items := n.List
+		if n.List == nil && !n.IsMap && n.Value != `` {
+			items = []*yamlNode{n}
+		}
+		for _, item := range items {
+			if _, ok := headerComponents[item.Value]; item.IsMap || item.List != nil || !ok {
+				errs = append(errs, &yamlError{item.Line, "unknown header component `" + item.Value + "`; expected license, copyright or spdx"})
+			}
+		}
+	}
+
+	if n := root.Get(`rules`); n != nil {
+		if n.IsMap {
+			errs = append(errs, &yamlError{root.KeyLine(`rules`), "`rules` must be a list of `<pattern> => <license>`"})
+		}
+		items := n.List
+		if n.List == nil && !n.IsMap && n.Value != `` {
+			items = []*yamlNode{n}
+		}
+		for _, item := range items {
+			rule, ok := parseRule(item.Value)
+			if item.IsMap || item.List != nil || !ok {
+				errs = append(errs, &yamlError{item.Line, "rules must be written `<pattern> => <license>`"})
+				continue
+			}
+			if err := checkGlob(rule.Pattern); err != `` {
+				errs = append(errs, &yamlError{item.Line, "bad pattern `" + rule.Pattern + "`: " + err})
+			}
+			if !known[rule.License] {
+				errs = append(errs, &yamlError{item.Line, "undefined license `" + string(rule.License) + "`"})
+			}
+		}
+	}
+
+	if n := root.Get(`licenses`); n != nil {
+		if !n.IsMap {
+			errs = append(errs, &yamlError{root.KeyLine(`licenses`), "`licenses` must map names to reference texts"})
+		} else if dir != `.` {
+			errs = append(errs, &yamlError{root.KeyLine(`licenses`), "`licenses` are only registered from the .weasel.yml at the root of the project"})
+		}
+		builtin := knownLicenses()
+		for i, name := range n.Keys {
+			text := n.Map[name]
+			if builtin[License(name)] {
+				errs = append(errs, &yamlError{n.KeyLines[i], "custom license `" + name + "` overlaps a license weasel already knows"})
+			}
+			if text.Value == `` {
+				errs = append(errs, &yamlError{n.KeyLines[i], "no reference text for `" + name + "`"})
+				continue
+			}
+			textFile := filepath.Join(textDir, filepath.FromSlash(text.Value))
+			if _, err := os.Stat(textFile); err != nil {
+				errs = append(errs, &yamlError{text.Line, "reference text of `" + name + "`: " + err.Error()})
+			}
+		}
+	}
+	return errs
+}
+
+// checkGlob describes what is wrong with an ignore pattern, if anything.
+func checkGlob(pattern string) string {
+	if strings.TrimSpace(pattern) == `` {
+		return "empty pattern"
+	}
+	for _, seg := range strings.Split(strings.TrimPrefix(pattern, `/`), `/`) {
+		if seg == `**` {
+			continue
+		}
+		if _, err := path.Match(seg, ``); err != nil {
+			return err.Error()
+		}
+	}
+	return ``
+}
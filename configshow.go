@@ -0,0 +1,142 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// shortEnvNames are the environment variables for single-letter options.
+var shortEnvNames = map[string]string{
+	`-f`: `WEASEL_LOG_FILE`,
+	`-d`: `WEASEL_SUBDIR`,
+	`-o`: `WEASEL_OUTPUT`,
+	`-q`: `WEASEL_QUIET`,
+	`-p`: `WEASEL_PROFILE`,
+	`-0`: `WEASEL_NUL_SEPARATED`,
+}
+
+// envName is the environment variable which sets an option, such as
+// WEASEL_MAX_UNKNOWN for --max-unknown.
+func envName(flag string) string {
+	if !strings.HasPrefix(flag, `--`) {
+		return shortEnvNames[flag]
+	}
+	return `WEASEL_` + strings.ToUpper(strings.Replace(flag[2:], `-`, `_`, -1))
+}
+
+// setting is the value of an option, and whether it came from the
+// `default`, a `flag` or an environment variable.
+type setting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// showConfig prints the options and .weasel.yml settings in force. Unless
+// effective, only those which differ from the defaults are shown.
+func showConfig(w io.Writer, settings []setting, effective bool) {
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+	fmt.Fprintln(w, "Options:")
+	for _, s := range settings {
+		if effective || s.Source != `default` {
+			fmt.Fprintf(w, "  %-20s %-30s (%s)\n", s.Name, strconv.Quote(s.Value), s.Source)
+		}
+	}
+
+	var names []string
+	filepath.Walk(`.`, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && info.Name() == `.git` {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && info.Name() == configName {
+			names = append(names, name)
+		}
+		return nil
+	})
+	var cfgs []*Config
+	if root := configFor(configName); root != nil && root.File != configName {
+		/* The user's weasel.yml stands in for the project's. */
+		cfgs = append(cfgs, root)
+	}
+	for _, name := range names {
+		cfgs = append(cfgs, configFor(name))
+	}
+	for _, cfg := range cfgs {
+		fmt.Fprintln(w, "Files beneath "+cfg.Dir+", from "+filepath.ToSlash(cfg.File)+":")
+		if effective || cfg.License != `` {
+			license := cfg.License
+			if license == `` {
+				license = `Apache`
+			}
+			fmt.Fprintf(w, "  %-20s %s\n", `license`, license)
+		}
+		if effective || cfg.Header != `` {
+			fmt.Fprintf(w, "  %-20s %s\n", `header`, strconv.Quote(cfg.Header))
+		}
+		if effective || cfg.CorporateHeader != `` {
+			fmt.Fprintf(w, "  %-20s %s\n", `corporate-header`, strconv.Quote(cfg.CorporateHeader))
+		}
+		if effective || len(cfg.Ignore) > 0 {
+			fmt.Fprintf(w, "  %-20s [%s]\n", `ignore`, strings.Join(cfg.Ignore, `, `))
+		}
+		if effective || len(cfg.Fixtures) > 0 {
+			fmt.Fprintf(w, "  %-20s [%s]\n", `fixtures`, strings.Join(cfg.Fixtures, `, `))
+		}
+		if effective || len(cfg.Rules) > 0 {
+			var rules []string
+			for _, rule := range cfg.Rules {
+				rules = append(rules, rule.Pattern+` => `+string(rule.License))
+			}
+			fmt.Fprintf(w, "  %-20s [%s]\n", `rules`, strings.Join(rules, `, `))
+		}
+		if effective || len(cfg.Aliases) > 0 {
+			var aliases []string
+			for lic, alias := range cfg.Aliases {
+				aliases = append(aliases, string(lic)+` => `+string(alias))
+			}
+			sort.Strings(aliases)
+			fmt.Fprintf(w, "  %-20s [%s]\n", `aliases`, strings.Join(aliases, `, `))
+		}
+		if effective || len(cfg.Require) > 0 {
+			fmt.Fprintf(w, "  %-20s [%s]\n", `require`, strings.Join(cfg.Require, `, `))
+		}
+		if effective || len(cfg.Holders) > 0 {
+			fmt.Fprintf(w, "  %-20s [%s]\n", `copyright-holders`, strings.Join(cfg.Holders, `, `))
+		}
+		var custom []string
+		for lic, text := range cfg.Licenses {
+			custom = append(custom, string(lic)+`: `+text)
+		}
+		sort.Strings(custom)
+		if cfg.Dir == `.` && (effective || len(custom) > 0) {
+			fmt.Fprintf(w, "  %-20s {%s}\n", `licenses`, strings.Join(custom, `, `))
+		}
+	}
+	if configFor(configName) == nil {
+		fmt.Fprintln(w, "Files elsewhere: no .weasel.yml, so the license is Apache.")
+	}
+}
@@ -0,0 +1,153 @@
+//go:build cshared
+// +build cshared
+
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// library serializes calls from the host program, since a scan keeps its
+// state in package variables, among them the custom licenses the matchers
+// read. Identifications may run at once, but not during a scan.
+var library sync.RWMutex
+
+// identifyResult is the result of weasel_identify.
+type identifyResult struct {
+	Licenses []License  `json:"licenses"`
+	SPDX     []License  `json:"spdx"`
+	Evidence []Evidence `json:"evidence"`
+}
+
+// weasel_identify returns, as JSON, the licenses the matchers find in text,
+// by weasel's names and by their SPDX identifiers, and the phrases which
+// identified them with their lines, as in the JSON report.
+//
+//export weasel_identify
+func weasel_identify(text *C.char) *C.char {
+	library.RLock()
+	defer library.RUnlock()
+	lics, evidence, err := identifyEvidence(strings.NewReader(C.GoString(text)))
+	if err != nil {
+		return libraryResult(nil, err)
+	}
+	id := identifyResult{Licenses: Uniq(lics), Evidence: evidence}
+	for _, lic := range id.Licenses {
+		id.SPDX = append(id.SPDX, lic.SPDX())
+	}
+	return libraryResult(id, nil)
+}
+
+// weasel_scan scans the directory at path as `weasel --format json` would
+// were it run there, and returns the report.
+//
+//export weasel_scan
+func weasel_scan(path *C.char) *C.char {
+	library.Lock()
+	defer library.Unlock()
+	r, err := scanDir(C.GoString(path))
+	return libraryResult(r, err)
+}
+
+// weasel_free releases a result of weasel_identify or weasel_scan.
+//
+//export weasel_free
+func weasel_free(result *C.char) {
+	C.free(unsafe.Pointer(result))
+}
+
+// scanDir scans dir without changing the working directory, which belongs
+// to the host program, and reports as the command does with its defaults.
+// As with scanFS, nothing is excluded by .gitignore.
+func scanDir(dir string) (Report, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return Report{}, err
+	}
+	if info, err := os.Stat(root); err != nil {
+		return Report{}, err
+	} else if !info.IsDir() {
+		return Report{}, errors.New(dir + " is not a directory")
+	}
+	explain = true
+	evidence.Lock()
+	evidence.byName = make(map[string][]Evidence)
+	evidence.Unlock()
+	unreadable.Lock()
+	unreadable.errs = nil
+	unreadable.Unlock()
+	files, extras, err := scanFS(os.DirFS(root))
+	if err != nil {
+		return Report{}, err
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []FileResult
+	failed := false
+	for _, name := range names {
+		lics := files[name]
+		licStr, ignore, undoc := describe(lics)
+		if ignore {
+			continue
+		}
+		results = append(results, FileResult{name, reported(lics), undoc, codesOf(lics), evidenceFor(name), suppressionsFor(name), reportDigest(name), name})
+		if undoc && !isReadError(licStr) {
+			/* Without --max-unknown, unknown licenses fail too. */
+			failed = true
+		}
+	}
+	stale := staleEntries()
+	r := newReport(results, extras, Conclude(files), failed || len(extras) > 0 || len(stale) > 0)
+	r.Root = root
+	r.Unreadable = len(unreadableErrors())
+	r.Ignored = ignoredPaths()
+	r.Suppressed = suppressionStats()
+	r.Stale = stale
+	return r, nil
+}
+
+// libraryResult encodes a result, or the error in its place, as a C string
+// for the host program to release with weasel_free.
+func libraryResult(v interface{}, err error) *C.char {
+	if err != nil {
+		v = map[string]string{`error`: err.Error()}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{`error`: err.Error()})
+	}
+	return C.CString(string(b))
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// phraseWords is how many of the opening words of a custom license's text
+// are matched exactly, as the hand-written phrases are.
+const phraseWords = 16
+
+// fuzzyThreshold is the fraction of a custom license's three-word shingles
+// a file must contain to bear it, tolerating reflowing, changed names and
+// small edits.
+const fuzzyThreshold = 0.8
+
+// fingerprint is the set of shingles of a license's text.
+type fingerprint struct {
+	license  License
+	shingles map[uint64]struct{}
+}
+
+func newFingerprint(license License, words []string) fingerprint {
+	fp := fingerprint{license: license, shingles: make(map[uint64]struct{})}
+	for i := 2; i < len(words); i++ {
+		fp.shingles[shingleHash(words[i-2], words[i-1], words[i])] = struct{}{}
+	}
+	return fp
+}
+
+// builtinFingerprints are those of the bundled SPDX texts, and
+// fingerprints those and the project's custom licenses'.
+var builtinFingerprints = spdxFingerprints()
+var fingerprints = builtinFingerprints
+
+/* shingleIndex holds every shingle of any fingerprint, and the indexes of the fingerprints bearing it. */
+var shingleIndex = indexShingles(fingerprints)
+
+func indexShingles(fps []fingerprint) map[uint64][]int {
+	index := make(map[uint64][]int)
+	for i, fp := range fps {
+		for h := range fp.shingles {
+			index[h] = append(index[h], i)
+		}
+	}
+	return index
+}
+
+// shingleHash is the 64-bit FNV-1a hash of `a b c`, computed without
+// allocating, as it is for every word of every file.
+func shingleHash(a, b, c string) uint64 {
+	h := uint64(14695981039346656037)
+	for _, s := range [...]string{a, ` `, b, ` `, c} {
+		for i := 0; i < len(s); i++ {
+			h ^= uint64(s[i])
+			h *= 1099511628211
+		}
+	}
+	return h
+}
+
+// shingler collects the shingles of a file which appear in some
+// fingerprint, so those of unrelated text cost no memory.
+type shingler struct {
+	prev  [2]string
+	count int
+	found map[uint64]struct{}
+}
+
+func newShingler() *shingler {
+	return &shingler{found: make(map[uint64]struct{})}
+}
+
+func (s *shingler) add(word string) {
+	if len(fingerprints) == 0 {
+		return
+	}
+	if s.count >= 2 {
+		h := shingleHash(s.prev[0], s.prev[1], word)
+		if _, ok := shingleIndex[h]; ok {
+			s.found[h] = struct{}{}
+		}
+	}
+	s.prev[0], s.prev[1] = s.prev[1], word
+	s.count++
+}
+
+// matches describes the licenses enough of whose shingles were seen. The
+// shingles found shortlist the fingerprints sharing any of them, so only
+// those are counted, not every shingle of every license.
+func (s *shingler) matches() []Evidence {
+	counts := make(map[int]int)
+	for h := range s.found {
+		for _, i := range shingleIndex[h] {
+			counts[i]++
+		}
+	}
+	var matched []int
+	for i, fp := range fingerprints {
+		if n, ok := counts[i]; ok && float64(n) >= fuzzyThreshold*float64(len(fp.shingles)) {
+			matched = append(matched, i)
+		}
+	}
+
+	var evidence []Evidence
+	for _, i := range matched {
+		fp := fingerprints[i]
+		if shadowed(i, matched, counts) {
+			continue
+		}
+		evidence = append(evidence, Evidence{
+			License: fp.license,
+			Phrase:  fmt.Sprintf("%d%% of the reference text", 100*counts[i]/len(fp.shingles)),
+		})
+	}
+	return evidence
+}
+
+// shadowed tells whether a matched text is mostly within another which
+// more of the file matched, as BSD-2-Clause is within BSD-3-Clause, so
+// only the better match is reported. Of texts matched equally, such as
+// GPL-2.0-only and GPL-2.0-or-later, the first is kept.
+func shadowed(i int, matched []int, counts map[int]int) bool {
+	for _, j := range matched {
+		if j == i || counts[j] < counts[i] || (counts[j] == counts[i] && j > i) {
+			continue
+		}
+		within := 0
+		for h := range fingerprints[i].shingles {
+			if _, ok := fingerprints[j].shingles[h]; ok {
+				within++
+			}
+		}
+		if float64(within) >= fuzzyThreshold*float64(len(fingerprints[i].shingles)) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinTexts drops the phrases of other licenses found within a reference
+// text the file matched, such as the conditions of the three clause
+// variant within the two clause one, or the license a documentation
+// license names, so that a license text is reported as the one license it
+// is. Tags are kept, as they declare a license rather than quote one.
+func withinTexts(phrases, fuzzy []Evidence) []Evidence {
+	var kept []Evidence
+	for _, ev := range phrases {
+		if ev.Phrase == `` || strings.HasPrefix(ev.Phrase, spdxTagPhrase) || !partOfText(ev, fuzzy) {
+			kept = append(kept, ev)
+		}
+	}
+	return kept
+}
+
+// partOfText tells whether a phrase is of the family of a matched text,
+// as sameLicense tells, or all its shingles are of the text. A phrase
+// naming the same license, if more narrowly, is not dropped.
+func partOfText(ev Evidence, fuzzy []Evidence) bool {
+	words := strings.Split(ev.Phrase, ` `)
+	for _, f := range fuzzy {
+		if f.License.SPDX() == ev.License.SPDX() {
+			continue
+		}
+		if sameLicense(f.License, ev.License) {
+			return true
+		}
+		for _, fp := range fingerprints {
+			if fp.license == f.License && len(words) >= 3 && fp.contains(words) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contains tells whether every shingle of the words is of the text.
+func (fp fingerprint) contains(words []string) bool {
+	for i := 2; i < len(words); i++ {
+		if _, ok := fp.shingles[shingleHash(words[i-2], words[i-1], words[i])]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// loadCustomLicenses registers the licenses named in the `licenses` of a
+// config, each with the path of its reference text relative to the
+// config's directory. Any earlier custom licenses are dropped.
+func loadCustomLicenses(cfg *Config) error {
+	var patterns []licensePattern
+	fps := append([]fingerprint(nil), builtinFingerprints...)
+
+	var names []string
+	if cfg != nil {
+		for name := range cfg.Licenses {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		textFile := cfg.textFile(License(name))
+		b, err := cfg.readText(License(name))
+		if err != nil {
+			return err
+		}
+		var words []string
+		if err := readWords(bytes.NewReader(b), func(word string, _ int) bool {
+			words = append(words, word)
+			return true
+		}); err != nil {
+			return errors.New(textFile + ": " + err.Error())
+		}
+		if len(words) == 0 {
+			return errors.New(textFile + ": no text to match")
+		}
+
+		phrase := words
+		if len(phrase) > phraseWords {
+			phrase = phrase[:phraseWords]
+		}
+		patterns = append(patterns, licensePattern{words: phrase, license: License(name)})
+
+		/* Texts too short for shingles are matched by their phrase alone. */
+		if len(words) < 3 {
+			continue
+		}
+		fps = append(fps, newFingerprint(License(name), words))
+	}
+
+	fingerprints, shingleIndex = fps, indexShingles(fps)
+	matcherPatterns, licenseAutomaton = matchers(patterns)
+	return nil
+}
+
+// loadProjectConfig forgets the configs of any earlier project and
+// registers the custom licenses of the one in the current directory.
+func loadProjectConfig() error {
+	configs.Lock()
+	configs.byDir = make(map[string]*Config)
+	configs.user, configs.userLoaded = nil, false
+	configs.Unlock()
+	return loadCustomLicenses(configFor(configName))
+}
+
+// nearestConfig returns the nearest `.weasel.yml` at or above dir which
+// registers custom licenses, or else the user's weasel.yml, for commands
+// such as identify which run on files rather than a project.
+func nearestConfig(dir string) *Config {
+	abs, err := filepath.Abs(dir)
+	if err == nil {
+		for {
+			if cfg := readConfig(filepath.ToSlash(abs), filepath.Join(abs, configName), readFile); cfg != nil && len(cfg.Licenses) != 0 {
+				return cfg
+			}
+			parent := filepath.Dir(abs)
+			if parent == abs {
+				break
+			}
+			abs = parent
+		}
+	}
+	configs.Lock()
+	defer configs.Unlock()
+	return userConfig()
+}
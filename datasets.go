@@ -0,0 +1,108 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// dataLicensePhrases identify the licenses of open datasets by their texts
+// and the notices which apply them.
+var dataLicensePhrases = []struct {
+	phrase  string
+	license License
+}{
+	{`Open Database License`, `ODbL-1.0`},
+	{`Open Data Commons Attribution License`, `ODC-By-1.0`},
+	{`Open Data Commons Public Domain Dedication and License`, `PDDL-1.0`},
+	{`Creative Commons Attribution 4.0`, `CC-BY-4.0`},
+	{`Creative Commons Attribution-ShareAlike 4.0`, `CC-BY-SA-4.0`},
+	{`CC0 1.0 Universal`, `CC0-1.0`},
+}
+
+// dataLicenseNames are the names dataset metadata gives those licenses:
+// their SPDX identifiers, and the short names of Frictionless data
+// packages and Hugging Face dataset cards.
+var dataLicenseNames = map[string]License{
+	`CC-BY-4.0`:    `CC-BY-4.0`,
+	`CC-BY-SA-4.0`: `CC-BY-SA-4.0`,
+	`CC0-1.0`:      `CC0-1.0`,
+	`ODbL-1.0`:     `ODbL-1.0`,
+	`odc-odbl`:     `ODbL-1.0`,
+	`odbl`:         `ODbL-1.0`,
+	`ODC-By-1.0`:   `ODC-By-1.0`,
+	`odc-by`:       `ODC-By-1.0`,
+	`PDDL-1.0`:     `PDDL-1.0`,
+	`odc-pddl`:     `PDDL-1.0`,
+	`pddl`:         `PDDL-1.0`,
+}
+
+// dataLicensePatterns match the texts of dataset licenses, and their names
+// as the `license` or `licenses` of metadata such as a datapackage.json, a
+// CITATION.cff or the front matter of a dataset card's README.md.
+func dataLicensePatterns() []licensePattern {
+	var patterns []licensePattern
+	for _, p := range dataLicensePhrases {
+		patterns = append(patterns, licensePattern{makeWords(p.phrase), p.license})
+	}
+
+	var names []string
+	for name := range dataLicenseNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		id := strings.Join(makeWords(name), ` `)
+		for _, key := range []string{`license`, `licenses`, `license name`, `licenses name`} {
+			patterns = append(patterns, licensePattern{makeWords(key + ` ` + id), dataLicenseNames[name]})
+		}
+	}
+	return patterns
+}
+
+// datasetFiles are the files of a data directory which state the license
+// of the dataset's files beside and beneath them, nearest first.
+var datasetFiles = []string{`datapackage.json`, `dataset-metadata.json`, `README.md`}
+
+// dataDir reports whether a slash-separated directory is, or is within, a
+// data directory such as `data` or `datasets`.
+func dataDir(dir string) bool {
+	for _, part := range strings.Split(dir, `/`) {
+		switch strings.ToLower(part) {
+		case `data`, `dataset`, `datasets`:
+			return true
+		}
+	}
+	return false
+}
+
+// dataLicenses returns those of lics which are licenses of datasets,
+// without their markers.
+func dataLicenses(lics []License) []License {
+	var data []License
+	for _, lic := range lics {
+		base, _ := lic.split()
+		for _, l := range dataLicenseNames {
+			if base == l {
+				data = append(data, base)
+				break
+			}
+		}
+	}
+	return Uniq(data)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+var dbFile string
+
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id       INTEGER PRIMARY KEY,
+	started  TEXT NOT NULL,
+	root     TEXT NOT NULL,
+	revision TEXT NOT NULL,
+	failed   INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS results (
+	run     INTEGER NOT NULL REFERENCES runs(id),
+	path    TEXT NOT NULL,
+	license TEXT NOT NULL,
+	error   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS results_path ON results (path);
+`
+
+// sqlQuote quotes s as an SQL string literal.
+func sqlQuote(s string) string {
+	return `'` + strings.Replace(s, `'`, `''`, -1) + `'`
+}
+
+// revision returns the commit checked out in the working directory, if any.
+func revision() string {
+	if !hasGit {
+		return ``
+	}
+	b, err := exec.Command(`git`, `rev-parse`, `HEAD`).Output()
+	if err != nil {
+		return ``
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// recordRun appends the results of a run to a SQLite database. It uses the
+// sqlite3 command, so that weasel itself needs no database driver.
+func recordRun(db string, started time.Time, files map[string][]License, failed bool) error {
+	if _, err := exec.LookPath(`sqlite3`); err != nil {
+		return errors.New("the sqlite3 command is required for --db")
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	var filenames []string
+	for filename := range files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	failedInt := 0
+	if failed {
+		failedInt = 1
+	}
+
+	var sql bytes.Buffer
+	sql.WriteString(dbSchema)
+	sql.WriteString("BEGIN;\n")
+	fmt.Fprintf(&sql, "INSERT INTO runs (started, root, revision, failed) VALUES (%s, %s, %s, %d);\n",
+		sqlQuote(started.UTC().Format(time.RFC3339)), sqlQuote(root), sqlQuote(revision()), failedInt)
+	for _, filename := range filenames {
+		_, ignore, undoc := describe(files[filename])
+		if ignore {
+			continue
+		}
+		errInt := 0
+		if undoc {
+			errInt = 1
+		}
+		for _, lic := range reported(files[filename]) {
+			fmt.Fprintf(&sql, "INSERT INTO results (run, path, license, error) VALUES ((SELECT MAX(id) FROM runs), %s, %s, %d);\n",
+				sqlQuote(filename), sqlQuote(string(lic)), errInt)
+		}
+	}
+	sql.WriteString("COMMIT;\n")
+
+	cmd := exec.Command(`sqlite3`, `-bail`, db)
+	cmd.Stdin = &sql
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.New(strings.TrimSpace(string(out)) + ": " + err.Error())
+	}
+	return nil
+}
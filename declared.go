@@ -0,0 +1,101 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// markDeclaredMismatch flags the license file of the project, and of each
+// Go module within it, with `Declared-Mismatch!` when the license it
+// declares is not the one most of its files' headers carry. Inherited
+// licenses, vendored files, files a rule governs and license files
+// themselves are not counted.
+func markDeclaredMismatch(files map[string][]License) {
+	modules := map[string]bool{`.`: true}
+	for name := range files {
+		if filepath.Base(name) == `go.mod` {
+			modules[path.Dir(filepath.ToSlash(name))] = true
+		}
+	}
+	moduleOf := func(name string) string {
+		for dir := path.Dir(filepath.ToSlash(name)); ; dir = path.Dir(dir) {
+			if modules[dir] || dir == `.` || dir == `/` {
+				return dir
+			}
+		}
+	}
+
+	declaredBy := make(map[string][]string)
+	declared := make(map[string][]License)
+	counts := make(map[string]map[License]int)
+	for name, lics := range files {
+		module := moduleOf(name)
+		if !modules[module] || Has(lics, License(`Ignore`)) {
+			continue
+		}
+		if licenseLike(name) {
+			if path.Dir(filepath.ToSlash(name)) == module {
+				declaredBy[module] = append(declaredBy[module], name)
+				for _, lic := range lics {
+					if base, _ := lic.split(); countable(base) {
+						declared[module] = append(declared[module], base)
+					}
+				}
+			}
+			continue
+		}
+		if _, ruled := ruleFor(name); ruled || Vendored(name) {
+			continue
+		}
+		if counts[module] == nil {
+			counts[module] = make(map[License]int)
+		}
+		for _, lic := range lics {
+			if base, suffix := lic.split(); countable(base) && !strings.Contains(suffix, `~`) {
+				counts[module][base]++
+			}
+		}
+	}
+
+	for module, names := range declaredBy {
+		dominant, most, tied := License(``), 0, false
+		for lic, n := range counts[module] {
+			if n > most {
+				dominant, most, tied = lic, n, false
+			} else if n == most {
+				tied = true
+			}
+		}
+		if len(declared[module]) == 0 || dominant == `` || tied || Has(declared[module], dominant) {
+			continue
+		}
+		sort.Strings(names)
+		files[names[0]] = append(files[names[0]], License(`Declared-Mismatch!`))
+	}
+}
+
+// countable tells whether a name weasel reports is the name of a license.
+func countable(lic License) bool {
+	if _, ok := notLicenses[lic]; ok {
+		return false
+	}
+	return !strings.HasPrefix(string(lic), `Unknown`) && !strings.HasPrefix(string(lic), `Error`) && !duplicate(lic)
+}
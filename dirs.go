@@ -0,0 +1,66 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// userDir finds one of weasel's directories outside of any project: the
+// override variable if set, then the XDG base directory variable, then the
+// XDG default beneath the home directory. On Windows, which has no XDG
+// defaults, the fallback is the platform directory given by native.
+func userDir(override, xdg, home string, native func() (string, error)) (string, error) {
+	if dir := os.Getenv(override); dir != `` {
+		return dir, nil
+	}
+	if dir := os.Getenv(xdg); dir != `` && filepath.IsAbs(dir) {
+		return filepath.Join(dir, `weasel`), nil
+	}
+	if runtime.GOOS == `windows` {
+		dir, err := native()
+		if err != nil {
+			return ``, err
+		}
+		return filepath.Join(dir, `weasel`), nil
+	}
+	h, err := os.UserHomeDir()
+	if err != nil {
+		return ``, err
+	}
+	if h == `` {
+		return ``, errors.New("no home directory")
+	}
+	return filepath.Join(h, home, `weasel`), nil
+}
+
+// cacheDir holds what weasel downloads, such as the SPDX License List.
+func cacheDir() (string, error) {
+	return userDir(`WEASEL_CACHE_DIR`, `XDG_CACHE_HOME`, `.cache`, os.UserCacheDir)
+}
+
+// configDir holds the user's weasel.yml, which applies to projects with no
+// .weasel.yml of their own.
+func configDir() (string, error) {
+	return userDir(`WEASEL_CONFIG_DIR`, `XDG_CONFIG_HOME`, `.config`, os.UserConfigDir)
+}
+
+// userConfigName is the name of the user's config within configDir.
+const userConfigName = `weasel.yml`
@@ -30,42 +30,52 @@ type Documented []string
 var documented Documented
 
 func recordDocumentedLicenses() {
-	f, err := os.Open(`LICENSE`)
+	f, err := openFile(`LICENSE`)
 	if err != nil {
 		fmt.Printf("Cannot open LICENSE file: %s!\n", err.Error())
+		return
 	}
+	defer f.Close()
 
 	s := bufio.NewScanner(f)
 	for s.Scan() {
 		line := strings.TrimSpace(s.Text())
 		if len(line) != 0 && line[0] == '@' {
-			documented = append(documented, line[1:])
+			documented = append(documented, nfc(line[1:]))
 		}
 	}
 }
 
 func (d Documented) Documents(name string) bool {
+	return d.documenting(name) != ``
+}
+
+// documenting returns the `@`-line which documents the file, if any.
+func (d Documented) documenting(name string) string {
+	return d.documents(nfc(filepath.ToSlash(name)))
+}
+
+func (d Documented) documents(name string) string {
 	for _, re := range d {
 		if ok, err := path.Match(re, name); ok && err == nil {
-			return true
+			return re
 		}
 	}
 	dir := path.Dir(name)
 	if dir != `` && dir != name {
-		return d.Documents(dir)
+		return d.documents(dir)
 	}
-	return false
+	return ``
 }
 
 func (d Documented) Extra() []string {
-	extra := make(map[string]struct{})
-	for _, s := range d {
-		extra[s] = struct{}{}
-	}
-
-	filepath.Walk(`.`, func(name string, info os.FileInfo, err error) error {
+	var names []string
+	walkTree(`.`, func(name string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
 		if filepath.Base(name) == `.git` {
@@ -76,13 +86,26 @@ func (d Documented) Extra() []string {
 			return nil
 		}
 
+		names = append(names, name)
+		return nil
+	})
+	return d.ExtraAmong(names)
+}
+
+// ExtraAmong returns the entries which describe none of the named files.
+func (d Documented) ExtraAmong(names []string) []string {
+	extra := make(map[string]struct{})
+	for _, s := range d {
+		extra[s] = struct{}{}
+	}
+
+	for _, name := range names {
 		for re := range extra {
-			if ok, err := path.Match(re, name); ok && err == nil {
+			if ok, err := path.Match(re, nfc(filepath.ToSlash(name))); ok && err == nil {
 				delete(extra, re)
 			}
 		}
-		return nil
-	})
+	}
 
 	var extraDoc []string
 	for re := range extra {
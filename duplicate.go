@@ -0,0 +1,93 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// duplicatePrefix begins the name reported for a license file whose text is
+// that of another, which follows in parentheses.
+const duplicatePrefix = `Duplicate-License(`
+
+// markDuplicateLicenses tags each license file whose whole text, with case
+// and whitespace folded, is that of another license file with
+// `Duplicate-License(<path>)`, naming the copy nearest the root. Vendored
+// copies are left alone, since they must travel with their components.
+func markDuplicateLicenses(files map[string][]License) {
+	byHash := make(map[uint64][]string)
+	texts := make(map[string][]byte)
+	for name, lics := range files {
+		if !licenseLike(name) || Has(lics, License(`Ignore`)) || Has(lics, License(`Empty`)) || Vendored(name) {
+			continue
+		}
+		f, err := openSource(name)
+		if err != nil {
+			continue
+		}
+		b, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil || len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+		hash := textHash(b)
+		byHash[hash] = append(byHash[hash], name)
+		texts[name] = b
+	}
+
+	/* Texts which only share a hash are told apart by the texts themselves. */
+	var groups [][]string
+	for _, names := range byHash {
+		if len(names) < 2 {
+			continue
+		}
+		byText := make(map[string][]string)
+		for _, name := range names {
+			text := foldedText(texts[name])
+			byText[text] = append(byText[text], name)
+		}
+		for _, names := range byText {
+			groups = append(groups, names)
+		}
+	}
+
+	for _, names := range groups {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Slice(names, func(i, j int) bool {
+			di := strings.Count(filepath.ToSlash(names[i]), `/`)
+			dj := strings.Count(filepath.ToSlash(names[j]), `/`)
+			if di != dj {
+				return di < dj
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names[1:] {
+			files[name] = append(files[name], License(duplicatePrefix+filepath.ToSlash(names[0])+`)`))
+		}
+	}
+}
+
+// duplicate tells whether a name weasel reports marks a duplicate license
+// file.
+func duplicate(lic License) bool {
+	return strings.HasPrefix(string(lic), duplicatePrefix)
+}
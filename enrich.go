@@ -0,0 +1,213 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sbomInput is the SPDX document `weasel enrich` adds its findings to, or
+// which `weasel verify` checks them against.
+var sbomInput string
+
+// spdxDocument is an SPDX 2 JSON document, of which only the files and
+// the licenses it declares are read. Everything else, the packages among
+// it, is written back as it was found.
+type spdxDocument struct {
+	fields     map[string]json.RawMessage
+	files      []map[string]json.RawMessage
+	extracted  []map[string]json.RawMessage
+	hasFiles   bool
+	hasLicRefs bool
+}
+
+// readSPDXDocument parses the SPDX JSON document in name.
+func readSPDXDocument(name string) (*spdxDocument, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	doc := &spdxDocument{}
+	if err := json.Unmarshal(b, &doc.fields); err != nil {
+		return nil, errors.New(name + ": " + err.Error())
+	}
+	if _, ok := doc.fields[`spdxVersion`]; !ok {
+		return nil, errors.New(name + ": not an SPDX JSON document, which has an spdxVersion")
+	}
+	if raw, ok := doc.fields[`files`]; ok {
+		doc.hasFiles = true
+		if err := json.Unmarshal(raw, &doc.files); err != nil {
+			return nil, errors.New(name + ": files: " + err.Error())
+		}
+	}
+	if raw, ok := doc.fields[`hasExtractedLicensingInfos`]; ok {
+		doc.hasLicRefs = true
+		if err := json.Unmarshal(raw, &doc.extracted); err != nil {
+			return nil, errors.New(name + ": hasExtractedLicensingInfos: " + err.Error())
+		}
+	}
+	return doc, nil
+}
+
+// licenseRefTexts describe the LicenseRef- identifiers weasel reports,
+// which a document naming them must declare.
+var licenseRefTexts = map[string]string{
+	`LicenseRef-GPL-or-LGPL`: `A version of the GNU General Public License or the GNU Lesser General Public License, which weasel does not tell apart.`,
+}
+
+// licenseInfoInFile is the SPDX licenseInfoInFiles of a file's licenses:
+// their identifiers, or NOASSERTION if none of them has one.
+func licenseInfoInFile(lics []License) []string {
+	var ids []string
+	for _, lic := range reported(lics) {
+		base, _ := lic.split()
+		id := string(base.SPDX())
+		if (spdxListed(id) || strings.HasPrefix(id, `LicenseRef-`)) && !hasString(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []string{`NOASSERTION`}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func hasString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// enrich sets the licenseInfoInFiles of each file the document lists to
+// the licenses found in it, declaring any LicenseRef- they use, adds its
+// SHA-256 to its checksums, and writes the document to w. It returns how many files it set, and the
+// names of those it lists which were not scanned.
+func (doc *spdxDocument) enrich(w io.Writer, files map[string][]License) (int, []string, error) {
+	byName := make(map[string]string, len(files))
+	for name := range files {
+		byName[filepath.ToSlash(name)] = name
+	}
+
+	declared := make(map[string]bool)
+	for _, info := range doc.extracted {
+		var id string
+		if json.Unmarshal(info[`licenseId`], &id) == nil {
+			declared[id] = true
+		}
+	}
+
+	enriched := 0
+	var missing []string
+	for _, file := range doc.files {
+		var fileName string
+		if err := json.Unmarshal(file[`fileName`], &fileName); err != nil {
+			continue
+		}
+		name, ok := byName[strings.TrimPrefix(path.Clean(fileName), `/`)]
+		if !ok {
+			missing = append(missing, fileName)
+			continue
+		}
+		ids := licenseInfoInFile(files[name])
+		for _, id := range ids {
+			if text, ok := licenseRefTexts[id]; ok && !declared[id] {
+				info, _ := json.Marshal(map[string]string{`licenseId`: id, `name`: id, `extractedText`: text})
+				var fields map[string]json.RawMessage
+				json.Unmarshal(info, &fields)
+				doc.extracted = append(doc.extracted, fields)
+				declared[id] = true
+			}
+		}
+		file[`licenseInfoInFiles`], _ = json.Marshal(ids)
+		addChecksum(file, name)
+		enriched++
+	}
+
+	if doc.hasFiles {
+		doc.fields[`files`], _ = json.Marshal(doc.files)
+	}
+	if doc.hasLicRefs || len(doc.extracted) > 0 {
+		doc.fields[`hasExtractedLicensingInfos`], _ = json.Marshal(doc.extracted)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent(``, `  `)
+	return enriched, missing, enc.Encode(doc.fields)
+}
+
+// addChecksum adds the SHA-256 of a file to the checksums of its entry,
+// unless they have one already.
+func addChecksum(file map[string]json.RawMessage, name string) {
+	var checksums []map[string]json.RawMessage
+	if raw, ok := file[`checksums`]; ok && json.Unmarshal(raw, &checksums) != nil {
+		return
+	}
+	for _, c := range checksums {
+		var algorithm string
+		if json.Unmarshal(c[`algorithm`], &algorithm) == nil && algorithm == `SHA256` {
+			return
+		}
+	}
+	digest, err := fileDigest(name)
+	if err != nil {
+		return
+	}
+	algorithm, _ := json.Marshal(`SHA256`)
+	value, _ := json.Marshal(digest)
+	checksums = append(checksums, map[string]json.RawMessage{`algorithm`: algorithm, `checksumValue`: value})
+	file[`checksums`], _ = json.Marshal(checksums)
+}
+
+// enrichSBOM adds the findings to the SPDX document, writing it to out or
+// else to w, and summing up what it did on stderr. The document was read
+// whole before the scan, so out may be the document itself.
+func enrichSBOM(w, stderr io.Writer, doc *spdxDocument, out string, files map[string][]License) error {
+	var n int
+	var missing []string
+	var err error
+	if out == `` {
+		n, missing, err = doc.enrich(w, files)
+	} else {
+		var f *os.File
+		if f, err = os.Create(out); err == nil {
+			n, missing, err = doc.enrich(f, files)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "Set the licenses of %d of the %d files %s lists.\n", n, len(doc.files), sbomInput)
+	for _, name := range missing {
+		fmt.Fprintln(stderr, "Not scanned: "+name)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// explain prints, beneath each file, what led to each of its licenses.
+// JSON and NDJSON output carry the same evidence regardless.
+var explain bool
+
+// Evidence is what in a file led to a license being identified: the
+// normalized words of a phrase and the lines they span, or for a fuzzy
+// match, how much of the reference text was found.
+type Evidence struct {
+	License   License `json:"license"`
+	Phrase    string  `json:"phrase"`
+	StartLine int     `json:"startLine,omitempty"`
+	EndLine   int     `json:"endLine,omitempty"`
+}
+
+var evidence = struct {
+	sync.Mutex
+	byName map[string][]Evidence
+}{byName: make(map[string][]Evidence)}
+
+func recordEvidence(name string, ev []Evidence) {
+	if !(explain || showsColumn(`confidence`)) || len(ev) == 0 {
+		return
+	}
+	evidence.Lock()
+	defer evidence.Unlock()
+	evidence.byName[name] = ev
+}
+
+// evidenceFor returns what led to each license of name. With
+// --reproducible it is in order of line, license and phrase, so the report
+// doesn't hang on the order in which the matchers were built.
+func evidenceFor(name string) []Evidence {
+	evidence.Lock()
+	ev := evidence.byName[name]
+	evidence.Unlock()
+	if !reproducible {
+		return ev
+	}
+	ev = append([]Evidence(nil), ev...)
+	sort.SliceStable(ev, func(i, j int) bool {
+		if ev[i].StartLine != ev[j].StartLine {
+			return ev[i].StartLine < ev[j].StartLine
+		}
+		if ev[i].License != ev[j].License {
+			return ev[i].License < ev[j].License
+		}
+		return ev[i].Phrase < ev[j].Phrase
+	})
+	return ev
+}
+
+func printEvidence(w io.Writer, name string) {
+	for _, ev := range evidenceFor(name) {
+		where := ``
+		switch {
+		case ev.StartLine == 0:
+		case ev.StartLine == ev.EndLine:
+			where = fmt.Sprintf(" on line %d", ev.StartLine)
+		default:
+			where = fmt.Sprintf(" on lines %d-%d", ev.StartLine, ev.EndLine)
+		}
+		fmt.Fprintf(w, "%46s   matched %q%s\n", ev.License, ev.Phrase, where)
+	}
+}
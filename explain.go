@@ -0,0 +1,144 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+)
+
+// inheritance holds the LICENSE file, or dataset metadata, from which each
+// file bearing no license of its own inherits.
+var inheritance = struct {
+	sync.Mutex
+	byName map[string]string
+}{byName: make(map[string]string)}
+
+func recordInheritance(name, from string) {
+	inheritance.Lock()
+	defer inheritance.Unlock()
+	inheritance.byName[name] = from
+}
+
+func inheritedFrom(name string) string {
+	inheritance.Lock()
+	defer inheritance.Unlock()
+	return inheritance.byName[name]
+}
+
+// explainFile prints each step of weasel's verdict on a file scanned: the
+// config governing it, the words it read, the matchers they were run
+// against and what matched, the overrides, ignore patterns and
+// inheritance applied, the license expected and the verdict. It returns
+// the exit status, nonzero if the file fails.
+func explainFile(w io.Writer, name string, files map[string][]License) int {
+	info, err := statFile(name)
+	if err == nil && info.IsDir() {
+		err = errors.New("it is a directory")
+	}
+	if err != nil {
+		fmt.Fprintln(w, "Cannot explain "+name+": "+err.Error()+"!")
+		return 1
+	}
+
+	fmt.Fprintf(w, "%-12s %s\n", "File:", filepath.ToSlash(name))
+	if cfg := configFor(name); cfg != nil {
+		fmt.Fprintf(w, "%-12s %s\n", "Config:", filepath.ToSlash(cfg.File))
+	} else {
+		fmt.Fprintf(w, "%-12s %s\n", "Config:", "none")
+	}
+
+	lics, ok := files[name]
+	if !ok {
+		for _, ig := range ignoredPaths() {
+			if ig.Path == filepath.ToSlash(name) {
+				fmt.Fprintf(w, "%-12s %s\n", "Ignored:", "the file is not read")
+				printSuppression(w, ig)
+				fmt.Fprintf(w, "%-12s %s\n", "Verdict:", "Ignored")
+				return 0
+			}
+		}
+		fmt.Fprintln(w, "Cannot explain "+name+": not a file weasel scans!")
+		return 1
+	}
+
+	if info.Size() == 0 {
+		fmt.Fprintf(w, "%-12s %s\n", "Tokens:", "none, the file is empty")
+	} else if f, err := openSource(name); err != nil {
+		fmt.Fprintf(w, "%-12s %s\n", "Tokens:", "unreadable: "+err.Error())
+	} else {
+		words, lines := 0, 0
+		err := readWords(f, func(_ string, lineNum int) bool {
+			words++
+			lines = lineNum
+			return true
+		})
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(w, "%-12s %s\n", "Tokens:", "unreadable: "+err.Error())
+		} else {
+			fmt.Fprintf(w, "%-12s %s on %s, with comment markers removed\n", "Tokens:", plural(words, `word`), plural(lines, `line`))
+		}
+	}
+	fmt.Fprintf(w, "%-12s %d exact phrases, %d fuzzy reference texts\n", "Matchers:", len(matcherPatterns), len(fingerprints))
+	if len(evidenceFor(name)) == 0 {
+		fmt.Fprintf(w, "%-12s %s\n", "Matched:", "nothing")
+	} else {
+		fmt.Fprintln(w, "Matched:")
+		printEvidence(w, name)
+	}
+
+	if len(suppressionsFor(name)) != 0 {
+		fmt.Fprintln(w, "Applied:")
+		printSuppressions(w, name)
+	}
+	if from := inheritedFrom(name); from != `` {
+		fmt.Fprintf(w, "%-12s %s\n", "Inherited:", "from "+filepath.ToSlash(from)+", the file bearing no license of its own")
+	}
+
+	expected := expectedLicense(name)
+	if _, ruled := ruleFor(name); ruled {
+		fmt.Fprintf(w, "%-12s %s, by a rule, and no other license\n", "Expected:", expected)
+	} else if documenting := documented.documenting(name); documenting != `` {
+		fmt.Fprintf(w, "%-12s %s, or those LICENSE documents by @%s\n", "Expected:", expected, documenting)
+	} else {
+		fmt.Fprintf(w, "%-12s %s, or those LICENSE documents\n", "Expected:", expected)
+	}
+
+	licStr, ignore, undoc := describe(lics)
+	verdict := "passes"
+	if ignore {
+		verdict = "is ignored"
+	} else if undoc {
+		verdict = withCodes("fails", codesOf(reported(lics)))
+	}
+	fmt.Fprintf(w, "%-12s %s %s\n", "Verdict:", licStr, verdict)
+	if undoc {
+		return 1
+	}
+	return 0
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return `1 ` + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
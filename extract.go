@@ -0,0 +1,187 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// extractDir is where --extract-licenses writes a text per license.
+var extractDir string
+
+// spdxTextURL is where the license-list-data project publishes the plain
+// text of each license on the SPDX License List, by identifier.
+var spdxTextURL = `https://raw.githubusercontent.com/spdx/license-list-data/main/text/`
+
+// reuseID is the identifier a license's text is named by in a REUSE
+// `LICENSES/` directory: its SPDX identifier, or a LicenseRef- for one
+// not on the list.
+func reuseID(lic License) string {
+	id := string(lic.SPDX())
+	if spdxListed(id) || strings.HasPrefix(id, `LicenseRef-`) {
+		return id
+	}
+	return `LicenseRef-` + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, id)
+}
+
+// fetchText downloads the SPDX License List's text of a license from
+// base, a directory of license-list-data's `text/` or a mirror of it.
+func fetchText(base, id string) (string, error) {
+	client := http.Client{Timeout: time.Minute}
+	resp, err := client.Get(base + id + `.txt`)
+	if err != nil {
+		return ``, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ``, errors.New(base + id + `.txt: ` + resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
+
+// foundText returns the text of a license found in the tree: the
+// reference text of a custom license, or a `LICENSE`, `COPYING` or
+// similar file under that license alone.
+func foundText(lic License, files map[string][]License, names []string) string {
+	for _, name := range names {
+		if cfg := configFor(name); cfg != nil && cfg.Licenses[lic] != `` {
+			if b, err := cfg.readText(lic); err == nil {
+				return string(b)
+			}
+		}
+	}
+	for _, name := range names {
+		if !licenseLike(name) {
+			continue
+		}
+		var lics []License
+		for _, l := range files[name] {
+			base, _ := l.split()
+			if _, ok := notLicenses[base]; !ok {
+				lics = append(lics, base)
+			}
+		}
+		if len(lics) != 1 || lics[0] != lic {
+			continue
+		}
+		b, err := readFile(name)
+		if err != nil {
+			continue
+		}
+		if name != `LICENSE` {
+			return string(b)
+		}
+		/* The project's LICENSE also documents files, with `@` lines. */
+		var lines []string
+		for _, line := range strings.Split(string(b), "\n") {
+			if !strings.HasPrefix(strings.TrimSpace(line), `@`) {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ``
+}
+
+// extractLicenses writes a text of every license detected into dir, as
+// `<id>.txt` in the REUSE layout. Texts on the SPDX License List are the
+// bundled or downloaded ones, or else downloaded now; the others are taken
+// from the tree. Files already in dir are left alone. With --offline,
+// nothing is downloaded, and a listed license with no text but one to
+// download is an error.
+func extractLicenses(w io.Writer, dir string, files map[string][]License) error {
+	var names []string
+	byLicense := make(map[License]bool)
+	for name, lics := range files {
+		names = append(names, name)
+		if Has(lics, License(`Ignore`)) {
+			continue
+		}
+		for _, lic := range lics {
+			base, _ := lic.split()
+			if !countable(base) {
+				continue
+			}
+			byLicense[base] = true
+		}
+	}
+	sort.Strings(names)
+	var lics []License
+	for lic := range byLicense {
+		lics = append(lics, lic)
+	}
+	sort.Sort(Licenses(lics))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	texts := licenseTexts()
+	var missing []string
+	for _, lic := range lics {
+		id := reuseID(lic)
+		name := filepath.Join(dir, id+`.txt`)
+		if _, err := os.Stat(name); err == nil {
+			fmt.Fprintln(w, "Kept "+name+".")
+			continue
+		}
+		text := ``
+		from := `the SPDX License List`
+		listed := spdxListed(id)
+		if b, ok := texts[id]; listed && ok {
+			text = string(b)
+		} else if listed && !offline {
+			var err error
+			if text, err = fetchText(spdxTextURL, id); err != nil {
+				fmt.Fprintln(w, "Cannot download "+id+": "+err.Error())
+			}
+		}
+		if text == `` {
+			from = `the tree`
+			text = foundText(lic, files, names)
+		}
+		if text == `` {
+			fmt.Fprintln(w, "No text found for "+string(lic)+".")
+			if listed && offline {
+				missing = append(missing, id)
+			}
+			continue
+		}
+		if err := ioutil.WriteFile(name, []byte(strings.TrimRight(text, "\n")+"\n"), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "Wrote "+name+" from "+from+".")
+	}
+	if len(missing) != 0 {
+		return errors.New("cannot download " + strings.Join(missing, `, `) + " with --offline")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// failFast stops the scan at the first file found in error.
+var failFast bool
+
+// errFailedFast ends the walk once a file is found in error.
+var errFailedFast = errors.New("stopped at the first error")
+
+var firstFailure struct {
+	sync.Mutex
+	stopped  int32
+	name     string
+	licenses []License
+}
+
+// failingFast tells whether a file has been found in error, so the rest
+// of the scan is abandoned.
+func failingFast() bool {
+	return atomic.LoadInt32(&firstFailure.stopped) != 0
+}
+
+// checkFast decides a file as soon as it is identified, when its verdict
+// needs nothing of the rest of the tree: that is, unless it has no
+// license of its own and may inherit one. files must be locked. base is
+// the baseline of --baseline, read once before the walk, or nil.
+func checkFast(name string, lics []License, base *baseline) {
+	if len(lics) == 0 || failingFast() {
+		return
+	}
+	single := map[string][]License{name: append([]License(nil), lics...)}
+	/* The checks below are made again by the full scan, whose record stands. */
+	recorded := len(suppressionsFor(name))
+	defer forgetSuppressions(name, recorded)
+	markUndocumented(single)
+	markVendored(single)
+	markFixtures(single)
+	if base != nil {
+		base.apply(baselineFile, single)
+	}
+	if _, ok := single[name]; !ok {
+		/* The baseline itself, which the full scan drops too. */
+		return
+	}
+
+	licStr, ignore, undoc := describe(single[name])
+	if ignore || !undoc || isReadError(licStr) {
+		return
+	}
+	firstFailure.Lock()
+	defer firstFailure.Unlock()
+	if firstFailure.name == `` {
+		firstFailure.name, firstFailure.licenses = name, single[name]
+		atomic.StoreInt32(&firstFailure.stopped, 1)
+	}
+}
+
+// failure returns the file found in error, and its licenses.
+func failure() (string, []License) {
+	firstFailure.Lock()
+	defer firstFailure.Unlock()
+	return firstFailure.name, firstFailure.licenses
+}
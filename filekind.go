@@ -22,7 +22,18 @@ import (
 )
 
 func filekind(name string) string {
-	b, err := exec.Command(`file`, `-b`, name).CombinedOutput()
+	cmd := exec.Command(`file`, `-b`, name)
+	if sourceFS != nil {
+		/* file cannot open what is not on disk, so is given the content. */
+		f, err := openFile(name)
+		if err != nil {
+			return ``
+		}
+		defer f.Close()
+		cmd = exec.Command(`file`, `-b`, `-`)
+		cmd.Stdin = f
+	}
+	b, err := cmd.CombinedOutput()
 	if err != nil {
 		return ``
 	}
@@ -0,0 +1,101 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// readFileList reads the paths listed one per line in the file name, or
+// in standard input if name is `-`, or separated by NULs if nul is set,
+// as `find -print0` and `git ls-files -z` write them. Each is made
+// absolute, since the list is relative to the directory weasel was run
+// from rather than the project root.
+func readFileList(name string, nul bool) ([]string, error) {
+	var in io.Reader = os.Stdin
+	if name != `-` {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	s := bufio.NewScanner(in)
+	s.Buffer(nil, 1<<20)
+	if nul {
+		s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			if i := bytes.IndexByte(data, 0); i >= 0 {
+				return i + 1, data[:i], nil
+			}
+			if atEOF && len(data) > 0 {
+				return len(data), data, nil
+			}
+			return 0, nil, nil
+		})
+	}
+	var paths []string
+	for s.Scan() {
+		line := s.Text()
+		if !nul {
+			line = strings.TrimSuffix(line, "\r")
+		}
+		if line == `` {
+			continue
+		}
+		abs, err := filepath.Abs(stripLongPath(line))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, abs)
+	}
+	return paths, s.Err()
+}
+
+// trackedFiles lists the files git tracks beneath root, relative to the
+// working directory, leaving out those which aren't checked out: outside a
+// sparse checkout, or deleted but not yet committed.
+func trackedFiles(root string) ([]string, error) {
+	if !hasGit {
+		return nil, errors.New("cannot list tracked files without git")
+	}
+	b, err := exec.Command(`git`, `ls-files`, `-z`, `-t`, `--`, root).Output()
+	if err != nil {
+		return nil, errors.New("cannot list tracked files: " + err.Error())
+	}
+	var names []string
+	for _, entry := range strings.Split(string(b), "\x00") {
+		/* `<tag> <path>`, where `S` marks a skip-worktree entry. */
+		if len(entry) < 3 || entry[0] == 'S' {
+			continue
+		}
+		name := filepath.FromSlash(entry[2:])
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
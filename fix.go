@@ -0,0 +1,201 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comcast/weasel/headers"
+)
+
+// fix inserts a header into each file of unknown license, and interactive
+// asks before each insertion.
+var fix, interactive bool
+
+// fixHeaders inserts the header of its expected license into every file
+// which bears no license and comments in a style weasel knows. When
+// interactive, each insertion is shown and, as read from in, accepted,
+// skipped, edited first or the rest abandoned. It returns the exit status.
+func fixHeaders(in io.Reader, w io.Writer, files map[string][]License) int {
+	var names []string
+	for name, lics := range files {
+		if licStr, ignore, _ := describe(lics); !ignore && licStr == `Unknown!` {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	s := bufio.NewScanner(in)
+	fixed, skipped := 0, 0
+	for i, name := range names {
+		style, ok := headers.StyleFor(name)
+		if !ok {
+			skipped++
+			continue
+		}
+		text, err := fixText(name)
+		if err != nil {
+			fmt.Fprintln(w, "Cannot fix "+filepath.ToSlash(name)+": "+err.Error())
+			skipped++
+			continue
+		}
+		header := headers.Render(text, style)
+
+		if interactive {
+			quit := false
+			for decided := false; !decided; {
+				fmt.Fprintf(w, "\n[%d/%d] %s\n", i+1, len(names), filepath.ToSlash(name))
+				for _, line := range strings.Split(strings.TrimSuffix(header, "\n"), "\n") {
+					fmt.Fprintln(w, strings.TrimRight("+ "+line, ` `))
+				}
+				fmt.Fprint(w, "(a)ccept, (s)kip, (e)dit or (q)uit? ")
+				if !s.Scan() {
+					fmt.Fprintln(w)
+					quit = true
+					break
+				}
+				switch strings.TrimSpace(s.Text()) {
+				case `a`:
+					decided = true
+				case `s`, ``:
+					header, decided = ``, true
+				case `q`:
+					quit = true
+				case `e`:
+					edited, err := editHeader(header)
+					if err != nil {
+						fmt.Fprintln(w, "Cannot edit the header: "+err.Error())
+					} else {
+						header = edited
+					}
+				}
+				if quit {
+					break
+				}
+			}
+			if quit {
+				skipped += len(names) - i
+				break
+			}
+			if header == `` {
+				skipped++
+				continue
+			}
+		}
+
+		if err := insertHeader(name, header); err != nil {
+			fmt.Fprintln(w, "Cannot fix "+filepath.ToSlash(name)+": "+err.Error())
+			skipped++
+			continue
+		}
+		fixed++
+	}
+	fmt.Fprintf(w, "Inserted headers into %d files, leaving %d of unknown license.\n", fixed, skipped)
+	if skipped > 0 {
+		return 1
+	}
+	return 0
+}
+
+// fixText returns the header a file ought to bear: the header its
+// .weasel.yml requires, or else a sample of its expected license crediting
+// the first copyright holder, followed by any corporate header.
+func fixText(name string) (string, error) {
+	cfg := configFor(name)
+	var text string
+	if cfg != nil && cfg.Header != `` {
+		text = cfg.Header
+	} else {
+		var err error
+		if text, err = fixtureHeader(expectedLicense(name)); err != nil {
+			return ``, err
+		}
+		holder := `The Authors`
+		if cfg != nil && len(cfg.Holders) > 0 {
+			holder = cfg.Holders[0]
+		}
+		text = strings.Replace(text, `Copyright 2017 The Authors`, `Copyright `+strconv.Itoa(time.Now().Year())+` `+holder, 1)
+	}
+	if cfg != nil && cfg.CorporateHeader != `` {
+		text += "\n\n" + cfg.CorporateHeader
+	}
+	return strings.TrimRight(text, "\n"), nil
+}
+
+// editHeader lets the user change a header in $EDITOR, or vi.
+func editHeader(header string) (string, error) {
+	f, err := ioutil.TempFile(``, `weasel-header-`)
+	if err != nil {
+		return ``, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(header)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return ``, err
+	}
+
+	editor := strings.Fields(os.Getenv(`EDITOR`))
+	if len(editor) == 0 {
+		editor = []string{`vi`}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return ``, err
+	}
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return ``, err
+	}
+	return strings.TrimRight(string(b), "\n") + "\n\n", nil
+}
+
+// insertHeader writes the header at the start of a file, after any line
+// which must come first, such as `#!` or `<?xml`.
+func insertHeader(name, header string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	first := ``
+	if strings.HasPrefix(content, `#!`) || strings.HasPrefix(content, `<?xml`) {
+		if i := strings.Index(content, "\n"); i >= 0 {
+			first, content = content[:i+1], content[i+1:]
+		} else {
+			first, content = content+"\n", ``
+		}
+	}
+	return ioutil.WriteFile(name, []byte(first+header+content), info.Mode().Perm())
+}
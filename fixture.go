@@ -0,0 +1,149 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/comcast/weasel/headers"
+)
+
+// fixtureHeaders are sample headers for the licenses weasel knows by name.
+var fixtureHeaders = map[License]string{
+	`Apache`: `Copyright 2017 The Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.`,
+	`MIT`: `Copyright 2017 The Authors
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.`,
+	`BSD`: `Copyright 2017 The Authors
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+3. Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products derived from this
+   software without specific prior written permission.`,
+	`GoBSD`: `Copyright 2017 The Go Authors. All rights reserved.
+Use of this source code is governed by a BSD-style
+license that can be found in the LICENSE file.`,
+	`ISC`: `Copyright 2017 The Authors
+
+Permission to use, copy, modify, and distribute this software for any
+purpose with or without fee is hereby granted, provided that the above
+copyright notice and this permission notice appear in all copies.`,
+	`X11`: `Copyright 2017 The Authors
+
+Licensed under the X11 License.`,
+	`WTFPL`: `Copyright 2017 The Authors
+
+This work is free. You can redistribute it and/or modify it under the
+terms of the WTFPL, Version 2, as published by Sam Hocevar.`,
+	`OFL`: `Copyright 2017 The Authors
+
+This Font Software is licensed under the SIL Open Font License, Version 1.1.
+This license is available with a FAQ at: https://openfontlicense.org`,
+	`GPL/LGPL`: `Copyright 2017 The Authors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.`,
+}
+
+// fixtureBodies are the code following the header of a fixture, by the
+// name of its comment style in headers.Styles.
+var fixtureBodies = map[string]string{
+	`go`:     "package fixture\n",
+	`c`:      "int fixture;\n",
+	`java`:   "class Fixture {}\n",
+	`python`: "fixture = True\n",
+	`shell`:  "true\n",
+	`yaml`:   "fixture: true\n",
+	`html`:   "<p>Fixture</p>\n",
+	`sql`:    "SELECT 1;\n",
+	`lisp`:   "(defvar fixture t)\n",
+}
+
+// fixtureHeader returns the header text for a license: a sample for those
+// weasel knows by name, the reference text of a custom license in the
+// nearest .weasel.yml registering any or the user's weasel.yml, or else
+// an SPDX tag.
+func fixtureHeader(lic License) (string, error) {
+	if text, ok := fixtureHeaders[lic]; ok {
+		return text, nil
+	}
+	if cfg := nearestConfig(`.`); cfg != nil {
+		if textFile, ok := cfg.Licenses[lic]; ok {
+			b, err := ioutil.ReadFile(filepath.Join(cfg.TextDir, filepath.FromSlash(textFile)))
+			return strings.TrimRight(string(b), "\n"), err
+		}
+	}
+	for _, line := range strings.Split(licenseList(), "\n") {
+		if strings.TrimSpace(line) == string(lic) {
+			return `SPDX-License-Identifier: ` + string(lic), nil
+		}
+	}
+	return ``, errors.New("unknown license `" + string(lic) + "`")
+}
+
+// genFixture writes a sample file bearing the license's header in the
+// comment style of a kind of source file.
+func genFixture(w io.Writer, lic License, style string) error {
+	s, ok := headers.Styles[style]
+	if !ok {
+		var styles []string
+		for _, name := range headers.StyleNames() {
+			styles = append(styles, "`"+name+"`")
+		}
+		return errors.New("unknown style `" + style + "`, expected one of " + strings.Join(styles, `, `))
+	}
+	text, err := fixtureHeader(lic)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(w, headers.Render(text, s)+fixtureBodies[style])
+	return nil
+}
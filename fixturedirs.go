@@ -0,0 +1,59 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// fixturing returns the first `fixtures` pattern matching the file or a
+// directory above it, if any.
+func (c *Config) fixturing(name string) string {
+	rel := c.rel(name)
+	for dir := rel; dir != `.` && dir != `/`; dir = path.Dir(dir) {
+		for _, pattern := range c.Fixtures {
+			if matchGlob(pattern, dir) {
+				return pattern
+			}
+		}
+	}
+	return ``
+}
+
+// Fixture reports whether a file is within the test fixtures of its config,
+// such as `testdata` or `fixtures` directories.
+func Fixture(name string) bool {
+	cfg := configFor(name)
+	return cfg != nil && cfg.fixturing(name) != ``
+}
+
+// markFixtures tags the files of test fixture directories, which are
+// reported but need not meet the project's requirements: what would be
+// wrong with any other file is left unmarked.
+func markFixtures(files map[string][]License) {
+	for name, licenses := range files {
+		if !Fixture(name) {
+			continue
+		}
+		for i, lic := range licenses {
+			base, suffix := lic.split()
+			licenses[i] = License(string(base) + strings.Replace(suffix, `!`, ``, -1))
+		}
+		files[name] = append(licenses, License(`Fixture`))
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+	"strings"
+	"unicode/utf16"
+)
+
+// fontNameIDs are the records of a font's name table which bear on its
+// license: the copyright notice, the license description and the URL of
+// the license.
+var fontNameIDs = []uint16{0, 13, 14}
+
+// isFont reports whether a file is a font whose metadata can be read.
+// WOFF2 fonts are compressed with Brotli, which the standard library
+// cannot decompress, so they are read as they stand.
+func isFont(lower string) bool {
+	for _, ext := range []string{`.ttf`, `.otf`, `.ttc`, `.woff`} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// fontText returns the copyright notice and license of a TrueType,
+// OpenType or WOFF font, from its name table, one record per line. It
+// fails for anything that doesn't parse as a font, which is then read as
+// it stands.
+func fontText(b []byte) ([]byte, bool) {
+	table, ok := nameTable(b)
+	if !ok || len(table) < 6 {
+		return nil, false
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	strs := int(binary.BigEndian.Uint16(table[4:]))
+
+	var text bytes.Buffer
+	seen := make(map[string]bool)
+	for _, id := range fontNameIDs {
+		for i := 0; i < count; i++ {
+			rec := 6 + 12*i
+			if rec+12 > len(table) {
+				break
+			}
+			platform := binary.BigEndian.Uint16(table[rec:])
+			encoding := binary.BigEndian.Uint16(table[rec+2:])
+			if binary.BigEndian.Uint16(table[rec+6:]) != id {
+				continue
+			}
+			start := strs + int(binary.BigEndian.Uint16(table[rec+10:]))
+			end := start + int(binary.BigEndian.Uint16(table[rec+8:]))
+			if end > len(table) {
+				continue
+			}
+			s, ok := nameString(platform, encoding, table[start:end])
+			if !ok || seen[s] {
+				continue
+			}
+			seen[s] = true
+			text.WriteString(s)
+			text.WriteString("\n")
+		}
+	}
+	return text.Bytes(), true
+}
+
+// nameTable finds the name table of a font, decompressing it from a WOFF
+// font. Of a collection, the first font's is taken.
+func nameTable(b []byte) ([]byte, bool) {
+	if len(b) < 12 {
+		return nil, false
+	}
+	switch string(b[:4]) {
+	case "\x00\x01\x00\x00", `OTTO`, `true`:
+		return sfntTable(b, 0, `name`)
+	case `ttcf`:
+		if len(b) < 16 || binary.BigEndian.Uint32(b[8:]) == 0 {
+			return nil, false
+		}
+		return sfntTable(b, int(binary.BigEndian.Uint32(b[12:])), `name`)
+	case `wOFF`:
+		if len(b) < 44 {
+			return nil, false
+		}
+		tables := int(binary.BigEndian.Uint16(b[12:]))
+		for i := 0; i < tables; i++ {
+			entry := 44 + 20*i
+			if entry+20 > len(b) {
+				return nil, false
+			}
+			if string(b[entry:entry+4]) != `name` {
+				continue
+			}
+			offset := int(binary.BigEndian.Uint32(b[entry+4:]))
+			compLength := int(binary.BigEndian.Uint32(b[entry+8:]))
+			origLength := int(binary.BigEndian.Uint32(b[entry+12:]))
+			if offset < 0 || compLength < 0 || offset+compLength > len(b) {
+				return nil, false
+			}
+			data := b[offset : offset+compLength]
+			if compLength == origLength {
+				return data, true
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, false
+			}
+			defer zr.Close()
+			table, err := ioutil.ReadAll(zr)
+			return table, err == nil
+		}
+	}
+	return nil, false
+}
+
+// sfntTable finds a table of the TrueType or OpenType font at offset.
+func sfntTable(b []byte, offset int, tag string) ([]byte, bool) {
+	if offset < 0 || offset+12 > len(b) {
+		return nil, false
+	}
+	tables := int(binary.BigEndian.Uint16(b[offset+4:]))
+	for i := 0; i < tables; i++ {
+		entry := offset + 12 + 16*i
+		if entry+16 > len(b) {
+			return nil, false
+		}
+		if string(b[entry:entry+4]) != tag {
+			continue
+		}
+		start := int(binary.BigEndian.Uint32(b[entry+8:]))
+		end := start + int(binary.BigEndian.Uint32(b[entry+12:]))
+		if start < 0 || end < start || end > len(b) {
+			return nil, false
+		}
+		return b[start:end], true
+	}
+	return nil, false
+}
+
+// nameString decodes a record of a name table: UTF-16 for the Unicode and
+// Windows platforms, and Mac Roman, taken as Latin-1, for the Macintosh.
+// Records in other encodings are skipped.
+func nameString(platform, encoding uint16, b []byte) (string, bool) {
+	switch {
+	case platform == 0 || (platform == 3 && (encoding == 0 || encoding == 1 || encoding == 10)):
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[2*i:])
+		}
+		return string(utf16.Decode(units)), true
+	case platform == 1 && encoding == 0:
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes), true
+	}
+	return ``, false
+}
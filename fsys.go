@@ -0,0 +1,104 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sourceFS is the filesystem being scanned, or nil for the operating
+// system's, where names may be absolute or reach above the working
+// directory. Names are still given in the platform's form, and converted
+// to the slash-separated paths of fs.FS as they are opened.
+var sourceFS fs.FS
+
+// scanFS scans the roots of an fs.FS, such as an embed.FS, a zip.Reader or
+// an fstest.MapFS, as scan does the working directory, honouring the
+// LICENSE, .dependency_license and .weasel.yml files within it, and
+// returning the unused LICENSE entries as scanProject does. Nothing within
+// is excluded by .gitignore, which only git can interpret. Its callers are
+// within weasel, such as weasel_scan of the shared library: package main
+// cannot be imported.
+func scanFS(fsys fs.FS, roots ...string) (map[string][]License, []string, error) {
+	sourceFS = fsys
+	defer func() { sourceFS = nil }()
+	if err := loadProjectConfig(); err != nil {
+		return nil, nil, err
+	}
+	if len(roots) == 0 {
+		roots = []string{`.`}
+	}
+	override = make(map[string][]License)
+	loadOverrides()
+	documented = nil
+	recordDocumentedLicenses()
+	files, err := scan(roots...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, documented.Extra(), nil
+}
+
+// fsPath converts a name to a path within sourceFS.
+func fsPath(name string) string {
+	return strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), `/`)
+}
+
+func openFile(name string) (fs.File, error) {
+	if sourceFS == nil {
+		return os.Open(name)
+	}
+	return sourceFS.Open(fsPath(name))
+}
+
+func statFile(name string) (fs.FileInfo, error) {
+	if sourceFS == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(sourceFS, fsPath(name))
+}
+
+func readFile(name string) ([]byte, error) {
+	if sourceFS == nil {
+		return ioutil.ReadFile(name)
+	}
+	return fs.ReadFile(sourceFS, fsPath(name))
+}
+
+// walkTree walks the tree beneath root as filepath.Walk does, whichever
+// filesystem is being scanned.
+func walkTree(root string, fn filepath.WalkFunc) error {
+	if sourceFS == nil {
+		return filepath.Walk(root, fn)
+	}
+	return fs.WalkDir(sourceFS, fsPath(root), func(p string, d fs.DirEntry, err error) error {
+		var info fs.FileInfo
+		if d != nil {
+			if i, infoErr := d.Info(); infoErr == nil {
+				info = i
+			} else if err == nil {
+				err = infoErr
+			}
+		}
+		return fn(filepath.FromSlash(p), info, err)
+	})
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// goPackage is the rollup of the Go files of one package.
+type goPackage struct {
+	ImportPath string   `json:"importPath"`
+	Dir        string   `json:"dir"`
+	License    string   `json:"license"`
+	Error      bool     `json:"error"`
+	Files      []string `json:"files"`
+}
+
+// modulePath reads the module path a go.mod declares.
+func modulePath(goMod string) string {
+	b, err := readFile(goMod)
+	if err != nil {
+		return ``
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == `module` {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ``
+}
+
+// goPackages groups the Go files, tests aside, by the directory of their
+// package, concluding the license of each as that of the whole tree is.
+// A package is named by its import path: beneath the module path of the
+// nearest go.mod, or for vendored packages the path beneath vendor/, or
+// else by its directory.
+func goPackages(files map[string][]License) []goPackage {
+	modules := make(map[string]string)
+	for name := range files {
+		if filepath.Base(name) == `go.mod` {
+			if mod := modulePath(name); mod != `` {
+				modules[path.Dir(filepath.ToSlash(name))] = mod
+			}
+		}
+	}
+	importPath := func(dir string) string {
+		if i := strings.LastIndex(`/`+dir, `/vendor/`); i >= 0 {
+			return dir[i+len(`vendor/`):]
+		}
+		for mod := dir; ; mod = path.Dir(mod) {
+			if p, ok := modules[mod]; ok {
+				return path.Join(p, strings.TrimPrefix(strings.TrimPrefix(dir, mod), `/`))
+			}
+			if mod == `.` || mod == `/` {
+				return dir
+			}
+		}
+	}
+
+	byDir := make(map[string]map[string][]License)
+	for name, lics := range files {
+		slashed := filepath.ToSlash(name)
+		if path.Ext(slashed) != `.go` || strings.HasSuffix(slashed, `_test.go`) || Has(lics, License(`Ignore`)) {
+			continue
+		}
+		dir := path.Dir(slashed)
+		if byDir[dir] == nil {
+			byDir[dir] = make(map[string][]License)
+		}
+		byDir[dir][name] = lics
+	}
+
+	var pkgs []goPackage
+	for dir, pkgFiles := range byDir {
+		pkg := goPackage{ImportPath: importPath(dir), Dir: dir, License: Conclude(pkgFiles), Files: []string{}}
+		if pkg.License == `` {
+			pkg.License = `Unknown`
+		}
+		for name, lics := range pkgFiles {
+			if _, _, undoc := describe(lics); undoc {
+				pkg.Error = true
+			}
+			pkg.Files = append(pkg.Files, displayPath(name))
+		}
+		sort.Strings(pkg.Files)
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].ImportPath < pkgs[j].ImportPath })
+	return pkgs
+}
+
+// writeGoPackages prints a row per Go package, or with `--format json` a
+// JSON array, returning 1 if any of the packages holds a file in error.
+func writeGoPackages(w io.Writer, files map[string][]License, format string) int {
+	pkgs := goPackages(files)
+	status := 0
+	for _, pkg := range pkgs {
+		if pkg.Error {
+			status = 1
+		}
+	}
+	if format == `json` {
+		if pkgs == nil {
+			pkgs = []goPackage{}
+		}
+		b, err := json.MarshalIndent(pkgs, ``, `  `)
+		if err != nil {
+			fmt.Fprintln(w, "Cannot list Go packages: "+err.Error())
+			return 1
+		}
+		fmt.Fprintln(w, string(b))
+		return status
+	}
+	for _, pkg := range pkgs {
+		errStr := ``
+		if pkg.Error {
+			errStr = `Error`
+		}
+		fmt.Fprintf(w, "%-6s%40s %s\n", errStr, pkg.License, pkg.ImportPath)
+	}
+	return status
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package headers renders license headers in the comment style of each
+// kind of source file, as weasel recognizes them. weasel renders the
+// headers it writes with it, and tools scaffolding new files can use it
+// to give them headers weasel accepts at once.
+//
+//	fmt.Print(headers.Render(headers.Apache(2017, `Comcast Corporation`), headers.Styles[`go`]))
+package headers
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Style is how a kind of source file comments: either each line with a
+// prefix, its Gutter, or a block with an opening, gutter and closing.
+type Style struct {
+	Open, Gutter, Close string
+}
+
+// Styles are the comment styles of the kinds of source files, by name.
+var Styles = map[string]Style{
+	`go`:     {Gutter: `// `},
+	`c`:      {Open: `/*`, Gutter: ` * `, Close: ` */`},
+	`java`:   {Open: `/*`, Gutter: ` * `, Close: ` */`},
+	`python`: {Gutter: `# `},
+	`shell`:  {Gutter: `# `},
+	`yaml`:   {Gutter: `# `},
+	`html`:   {Open: `<!--`, Gutter: `  `, Close: `-->`},
+	`sql`:    {Gutter: `-- `},
+	`lisp`:   {Gutter: `;; `},
+}
+
+// extensionStyles are the names of the styles of files, by extension.
+var extensionStyles = map[string]string{
+	`.go`:    `go`,
+	`.c`:     `c`,
+	`.h`:     `c`,
+	`.cc`:    `c`,
+	`.cpp`:   `c`,
+	`.hpp`:   `c`,
+	`.css`:   `c`,
+	`.java`:  `java`,
+	`.js`:    `java`,
+	`.ts`:    `java`,
+	`.kt`:    `java`,
+	`.scala`: `java`,
+	`.rs`:    `java`,
+	`.swift`: `java`,
+	`.py`:    `python`,
+	`.rb`:    `python`,
+	`.pl`:    `python`,
+	`.sh`:    `shell`,
+	`.bash`:  `shell`,
+	`.yml`:   `yaml`,
+	`.yaml`:  `yaml`,
+	`.toml`:  `yaml`,
+	`.html`:  `html`,
+	`.xml`:   `html`,
+	`.md`:    `html`,
+	`.sql`:   `sql`,
+	`.lisp`:  `lisp`,
+	`.el`:    `lisp`,
+	`.clj`:   `lisp`,
+}
+
+// StyleFor returns the comment style of a file by its extension, or false
+// for a kind of file it doesn't know.
+func StyleFor(name string) (Style, bool) {
+	style, ok := Styles[extensionStyles[strings.ToLower(path.Ext(name))]]
+	return style, ok
+}
+
+// StyleNames returns the names of the styles, sorted.
+func StyleNames() []string {
+	var names []string
+	for name := range Styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ASF is the header of files of the Apache Software Foundation's projects.
+const ASF = `Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.`
+
+// apache is the notice the Apache License's appendix recommends, after
+// the copyright line.
+const apache = `Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.`
+
+// Apache returns the header the Apache License, Version 2.0 recommends,
+// crediting the copyright holder.
+func Apache(year int, holder string) string {
+	return `Copyright ` + strconv.Itoa(year) + ` ` + holder + "\n\n" + apache
+}
+
+// SPDX returns a header tagging a license by its SPDX identifier, such as
+// `Apache-2.0`, crediting the copyright holder.
+func SPDX(year int, holder, id string) string {
+	return `Copyright ` + strconv.Itoa(year) + ` ` + holder + "\n" + `SPDX-License-Identifier: ` + id
+}
+
+// Render comments the text of a header in a style, followed by a blank
+// line. The text may be any header, such as a custom license's, or one
+// a .weasel.yml requires, and lines are not rewrapped, so it is compared
+// word for word as written.
+func Render(text string, s Style) string {
+	var b strings.Builder
+	if s.Open != `` {
+		b.WriteString(s.Open + "\n")
+	}
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		b.WriteString(strings.TrimRight(s.Gutter+line, ` `) + "\n")
+	}
+	if s.Close != `` {
+		b.WriteString(s.Close + "\n")
+	}
+	return b.String() + "\n"
+}
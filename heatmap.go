@@ -0,0 +1,87 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// reportKind is what the text format lists: the `files`, or a `heatmap`
+// of the directories holding the most files in error.
+var reportKind = `files`
+
+// heat is how many of the files directly within a directory are in error.
+type heat struct {
+	dir                   string
+	files, unknown, undoc int
+}
+
+func (h heat) errors() int {
+	return h.unknown + h.undoc
+}
+
+// writeHeatmap prints a row per directory holding files in error, worst
+// first: how many of its files are of unknown license, how many others
+// are undocumented, and what share of its files they are. Files beneath
+// its subdirectories are counted in theirs.
+func writeHeatmap(w io.Writer, files map[string][]License) {
+	byDir := make(map[string]*heat)
+	for name, lics := range files {
+		licStr, ignore, undoc := describe(lics)
+		if ignore {
+			continue
+		}
+		dir := path.Dir(filepath.ToSlash(name))
+		h := byDir[dir]
+		if h == nil {
+			h = &heat{dir: dir}
+			byDir[dir] = h
+		}
+		h.files++
+		switch {
+		case !undoc || isReadError(licStr):
+		case strings.HasPrefix(licStr, `Unknown`):
+			h.unknown++
+		default:
+			h.undoc++
+		}
+	}
+
+	var heats []heat
+	for _, h := range byDir {
+		if h.errors() > 0 {
+			heats = append(heats, *h)
+		}
+	}
+	sort.Slice(heats, func(i, j int) bool {
+		if heats[i].errors() != heats[j].errors() {
+			return heats[i].errors() > heats[j].errors()
+		}
+		return heats[i].dir < heats[j].dir
+	})
+
+	fmt.Fprintf(w, "%8s %12s %6s %6s %s\n", "Unknown", "Undocumented", "Files", "Share", "Directory")
+	for _, h := range heats {
+		share := fmt.Sprintf("%.1f%%", 100*float64(h.errors())/float64(h.files))
+		fmt.Fprintf(w, "%8d %12d %6d %6s %s\n", h.unknown, h.undoc, h.files, share, displayPath(h.dir))
+	}
+}
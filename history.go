@@ -0,0 +1,205 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tagSummary is the license composition of the tree at one tag.
+type tagSummary struct {
+	Tag      string
+	Files    int
+	Errors   int
+	Licenses map[License]int
+}
+
+// recentTags returns up to n tags, oldest first.
+func recentTags(n int) ([]string, error) {
+	b, err := exec.Command(`git`, `tag`, `--sort=-creatordate`).Output()
+	if err != nil {
+		return nil, errors.New("cannot list tags: " + err.Error())
+	}
+	tags := strings.Fields(string(b))
+	if len(tags) > n {
+		tags = tags[:n]
+	}
+	for i, j := 0, len(tags)-1; i < j; i, j = i+1, j-1 {
+		tags[i], tags[j] = tags[j], tags[i]
+	}
+	return tags, nil
+}
+
+// extractTree writes the tree of the given revision into dir.
+func extractTree(rev, dir string) error {
+	cmd := exec.Command(`git`, `archive`, `--format=tar`, rev)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cmd.Wait()
+			return err
+		}
+
+		name := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(name, 0777)
+		case tar.TypeSymlink:
+			err = os.Symlink(hdr.Linkname, name)
+		case tar.TypeReg, tar.TypeRegA:
+			var f *os.File
+			if f, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0777); err == nil {
+				_, err = io.Copy(f, tr)
+				f.Close()
+			}
+		}
+		if err != nil {
+			cmd.Wait()
+			return err
+		}
+	}
+	return cmd.Wait()
+}
+
+// summarize counts the files, errors and licenses of a scan.
+func summarize(tag string, files map[string][]License) tagSummary {
+	sum := tagSummary{Tag: tag, Licenses: make(map[License]int)}
+	for _, lics := range files {
+		_, ignore, undoc := describe(lics)
+		if ignore {
+			continue
+		}
+		sum.Files++
+		if undoc {
+			sum.Errors++
+		}
+		if len(lics) == 0 {
+			sum.Licenses[License(`Unknown`)]++
+		}
+		for _, lic := range lics {
+			base, _ := lic.split()
+			if useSPDX {
+				base = base.SPDX()
+			}
+			sum.Licenses[base]++
+		}
+	}
+	return sum
+}
+
+// scanTag scans the tree at the given tag, as though it were checked out.
+func scanTag(tag string) (tagSummary, error) {
+	dir, err := ioutil.TempDir(``, `weasel-history-`)
+	if err != nil {
+		return tagSummary{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := extractTree(tag, dir); err != nil {
+		return tagSummary{}, errors.New("cannot extract " + tag + ": " + err.Error())
+	}
+
+	files, _, err := scanProject(dir)
+	if err != nil {
+		return tagSummary{}, err
+	}
+	return summarize(tag, files), nil
+}
+
+// history scans each of the last n tags and prints how the license
+// composition and error count changed from one to the next.
+func history(w io.Writer, n int) error {
+	if !hasGit {
+		return errors.New("git is required for history")
+	}
+
+	tags, err := recentTags(n)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return errors.New("no tags found")
+	}
+
+	var prev *tagSummary
+	for _, tag := range tags {
+		sum, err := scanTag(tag)
+		if err != nil {
+			return err
+		}
+
+		var lics []License
+		for lic := range sum.Licenses {
+			lics = append(lics, lic)
+		}
+		if prev != nil {
+			for lic := range prev.Licenses {
+				if _, ok := sum.Licenses[lic]; !ok {
+					lics = append(lics, lic)
+				}
+			}
+		}
+		sort.Sort(Licenses(lics))
+
+		var parts []string
+		for _, lic := range lics {
+			part := fmt.Sprintf("%s: %d", lic, sum.Licenses[lic])
+			if prev != nil {
+				part += delta(sum.Licenses[lic] - prev.Licenses[lic])
+			}
+			parts = append(parts, part)
+		}
+
+		errStr := fmt.Sprint(sum.Errors)
+		filesStr := fmt.Sprint(sum.Files)
+		if prev != nil {
+			errStr += delta(sum.Errors - prev.Errors)
+			filesStr += delta(sum.Files - prev.Files)
+		}
+		fmt.Fprintf(w, "%-20s %12s files %12s errors  %s\n", tag, filesStr, errStr, strings.Join(parts, `, `))
+		prev = &sum
+	}
+	return nil
+}
+
+func delta(d int) string {
+	if d == 0 {
+		return ``
+	}
+	return fmt.Sprintf(" (%+d)", d)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// identifyConfig is the config whose custom licenses identify knows: that
+// of the project of the first file, or of the working directory for
+// standard input.
+func identifyConfig(names []string) *Config {
+	dir := `.`
+	if len(names) != 0 && names[0] != `-` {
+		dir = filepath.Dir(names[0])
+	}
+	return nearestConfig(dir)
+}
+
+// identify prints the licenses detected in each named file, or in standard
+// input for `-`, without consulting overrides or LICENSE. It returns the
+// exit status.
+func identify(w io.Writer, names []string) int {
+	if len(names) == 0 {
+		names = []string{`-`}
+	}
+
+	code := 0
+	for _, name := range names {
+		var lics []License
+		var err error
+		if name == `-` {
+			lics, err = identifyLicenses(os.Stdin)
+		} else {
+			lics, err = fileLicenses(name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot identify "+name+": "+err.Error())
+			code = 1
+			continue
+		}
+
+		var licStrs []string
+		for _, lic := range Uniq(lics) {
+			if useSPDX {
+				lic = lic.SPDX()
+			}
+			licStrs = append(licStrs, string(lic))
+		}
+		licStr := strings.Join(licStrs, `, `)
+		if licStr == `` {
+			licStr = `Unknown`
+		}
+
+		if len(names) == 1 {
+			fmt.Fprintln(w, licStr)
+		} else {
+			fmt.Fprintf(w, "%40s %s\n", licStr, name)
+		}
+	}
+	return code
+}
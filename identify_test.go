@@ -0,0 +1,75 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// eula is the reference text of a custom license, long enough to be
+// matched by its opening phrase and its shingles alike.
+const eula = `This software is the confidential property of MyCorp and may be used
+only by employees of MyCorp for the internal business of MyCorp, and by
+no one else, under any circumstances whatsoever, without the prior
+written consent of the MyCorp legal department.`
+
+func TestIdentifyProjectConfig(t *testing.T) {
+	project := inTree(t, map[string]string{
+		`.weasel.yml`:    "licenses:\n  MyCorp-EULA: legal/eula.txt\n",
+		`legal/eula.txt`: eula + "\n",
+		`src/a.go`:       "/*\n" + eula + "\n*/\npackage src\n",
+	})
+	outside := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(outside, `a.go`), []byte("/*\n"+eula+"\n*/\npackage src\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		file string
+		want string
+	}{
+		{`from the root`, project, `src/a.go`, "MyCorp-EULA\n"},
+		{`from a subdirectory`, filepath.Join(project, `src`), `a.go`, "MyCorp-EULA\n"},
+		{`by absolute path`, outside, filepath.Join(project, `src`, `a.go`), "MyCorp-EULA\n"},
+		{`outside the project`, project, filepath.Join(outside, `a.go`), "Unknown\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := loadCustomLicenses(nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chdir(tc.dir); err != nil {
+				t.Fatal(err)
+			}
+			names := []string{tc.file}
+			if err := loadCustomLicenses(identifyConfig(names)); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			identify(&out, names)
+			if got := out.String(); got != tc.want {
+				t.Errorf("identify %s = %q, want %q", tc.file, got, tc.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"os/exec"
+	"strings"
 )
 
 var hasGit bool
@@ -29,9 +30,25 @@ func init() {
 }
 
 func Ignored(f string) bool {
-	if hasGit {
-		_, err := exec.Command(`git`, `check-ignore`, `-q`, f).CombinedOutput()
-		return err == nil
+	source, _ := gitIgnoredBy(f)
+	return source != ``
+}
+
+// gitIgnoredBy returns the .gitignore entry excluding a file, as source
+// and pattern, or empty strings if it isn't excluded.
+func gitIgnoredBy(f string) (string, string) {
+	if !hasGit || sourceFS != nil {
+		return ``, ``
+	}
+	out, err := exec.Command(`git`, `check-ignore`, `-v`, f).Output()
+	if err != nil {
+		return ``, ``
+	}
+	/* `<source>:<line>:<pattern>\t<path>` */
+	line := strings.SplitN(strings.TrimRight(string(out), "\n"), "\t", 2)[0]
+	parts := strings.SplitN(line, `:`, 3)
+	if len(parts) != 3 {
+		return line, ``
 	}
-	return false
+	return parts[0] + `:` + parts[1], parts[2]
 }
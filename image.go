@@ -0,0 +1,420 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// isImageURL tells whether a target names an image in a registry, as
+// `oci://<registry>/<repository>:<tag>` or `@<digest>`.
+func isImageURL(target string) bool {
+	return strings.HasPrefix(target, `oci://`)
+}
+
+// registry speaks the OCI distribution API to one repository, with the
+// credentials `docker login` left in the Docker config, if any.
+type registry struct {
+	base  string
+	host  string
+	repo  string
+	basic string /* base64 of `user:password`. */
+	token string
+}
+
+// manifestTypes are the manifests and indexes of images weasel reads.
+var manifestTypes = []string{
+	`application/vnd.oci.image.index.v1+json`,
+	`application/vnd.oci.image.manifest.v1+json`,
+	`application/vnd.docker.distribution.manifest.list.v2+json`,
+	`application/vnd.docker.distribution.manifest.v2+json`,
+}
+
+// newImageFS streams the layers of an image from its registry, applying
+// each over the last as a container runtime would, and presents the files
+// of the result. Nothing is written to disk and no daemon is needed.
+func newImageFS(target string) (*objectFS, error) {
+	r, ref, err := parseImage(strings.TrimPrefix(target, `oci://`))
+	if err != nil {
+		return nil, err
+	}
+	m, err := r.manifest(ref)
+	if err != nil {
+		return nil, err
+	}
+	store := memoryStore{}
+	for _, layer := range m.Layers {
+		if err := r.applyLayer(store, layer); err != nil {
+			return nil, errors.New("layer " + layer.Digest + ": " + err.Error())
+		}
+	}
+	var objects []objectInfo
+	for name, b := range store {
+		objects = append(objects, objectInfo{name, int64(len(b)), time.Time{}})
+	}
+	return newObjectTree(store, objects, ``), nil
+}
+
+// parseImage splits `<registry>/<repository>:<tag>` into the registry and
+// the reference, a tag or digest, which is `latest` if not given.
+func parseImage(image string) (*registry, string, error) {
+	slash := strings.Index(image, `/`)
+	if slash <= 0 || slash == len(image)-1 {
+		return nil, ``, errors.New("expected oci://<registry>/<repository>[:<tag>|@<digest>]")
+	}
+	host, repo := image[:slash], image[slash+1:]
+	ref := `latest`
+	if at := strings.Index(repo, `@`); at >= 0 {
+		repo, ref = repo[:at], repo[at+1:]
+	} else if colon := strings.LastIndex(repo, `:`); colon > strings.LastIndex(repo, `/`) {
+		repo, ref = repo[:colon], repo[colon+1:]
+	}
+
+	r := &registry{base: `https://` + host, host: host, repo: repo}
+	if host == `docker.io` || host == `index.docker.io` {
+		r.base = `https://registry-1.docker.io`
+		if !strings.Contains(repo, `/`) {
+			r.repo = `library/` + repo
+		}
+	}
+	/* As with docker, registries on this machine are spoken to in the clear. */
+	if h := strings.Split(host, `:`)[0]; h == `localhost` || h == `127.0.0.1` {
+		r.base = `http://` + host
+	}
+	r.basic = dockerAuth(host)
+	return r, ref, nil
+}
+
+// dockerAuth returns the credentials for a registry in the Docker config,
+// `config.json` in $DOCKER_CONFIG or ~/.docker.
+func dockerAuth(host string) string {
+	dir := os.Getenv(`DOCKER_CONFIG`)
+	if dir == `` {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ``
+		}
+		dir = filepath.Join(home, `.docker`)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, `config.json`))
+	if err != nil {
+		return ``
+	}
+	var cfg struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if json.Unmarshal(b, &cfg) != nil {
+		return ``
+	}
+	keys := []string{host, `https://` + host}
+	if host == `docker.io` || host == `index.docker.io` {
+		keys = append(keys, `https://index.docker.io/v1/`)
+	}
+	for _, key := range keys {
+		if a, ok := cfg.Auths[key]; ok {
+			return a.Auth
+		}
+	}
+	return ``
+}
+
+// get requests a path of the repository, authenticating as the registry
+// asks when first refused.
+func (r *registry) get(what string, accept []string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(`GET`, r.base+`/v2/`+r.repo+`/`+what, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range accept {
+			req.Header.Add(`Accept`, a)
+		}
+		if r.token != `` {
+			req.Header.Set(`Authorization`, `Bearer `+r.token)
+		} else if r.basic != `` && attempt > 0 {
+			req.Header.Set(`Authorization`, `Basic `+r.basic)
+		}
+		resp, err := objectClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := resp.Header.Get(`WWW-Authenticate`)
+			resp.Body.Close()
+			if err := r.authenticate(challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			resp.Body.Close()
+			return nil, errors.New(r.host + " responded " + resp.Status + " for " + r.repo + "/" + what)
+		}
+		return resp, nil
+	}
+}
+
+// authenticate answers a `WWW-Authenticate` challenge: for `Bearer`, by
+// fetching a token to pull the repository from the realm named.
+func (r *registry) authenticate(challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	if strings.EqualFold(scheme, `Basic`) {
+		if r.basic == `` {
+			return errors.New(r.host + " requires credentials; run docker login")
+		}
+		return nil
+	}
+	if !strings.EqualFold(scheme, `Bearer`) || params[`realm`] == `` {
+		return errors.New(r.host + " refused access: " + challenge)
+	}
+	q := url.Values{}
+	if params[`service`] != `` {
+		q.Set(`service`, params[`service`])
+	}
+	scope := params[`scope`]
+	if scope == `` {
+		scope = `repository:` + r.repo + `:pull`
+	}
+	q.Set(`scope`, scope)
+	sep := `?`
+	if strings.Contains(params[`realm`], `?`) {
+		sep = `&`
+	}
+	req, err := http.NewRequest(`GET`, params[`realm`]+sep+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if r.basic != `` {
+		req.Header.Set(`Authorization`, `Basic `+r.basic)
+	}
+	body, err := fetch(req)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(body).Decode(&tok); err != nil {
+		return err
+	}
+	r.token = tok.Token
+	if r.token == `` {
+		r.token = tok.AccessToken
+	}
+	if r.token == `` {
+		return errors.New(params[`realm`] + " gave no token")
+	}
+	return nil
+}
+
+// parseChallenge splits `Bearer realm="...",service="..."` into its scheme
+// and parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(challenge), ` `, 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+	rest := parts[1]
+	for rest != `` {
+		eq := strings.Index(rest, `=`)
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		value := ``
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			value, rest = rest[1:end+1], rest[end+1:]
+			if strings.HasPrefix(rest, `"`) {
+				rest = rest[1:]
+			}
+		} else if comma := strings.Index(rest, `,`); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ``
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, `, `)
+	}
+	return parts[0], params
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Platform  *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests"` /* Of an index. */
+	Layers    []descriptor `json:"layers"`
+}
+
+// manifest fetches the manifest of an image, choosing from an index the
+// image for linux on this machine's architecture, or else the first.
+func (r *registry) manifest(ref string) (*manifest, error) {
+	for depth := 0; depth < 2; depth++ {
+		resp, err := r.get(`manifests/`+ref, manifestTypes)
+		if err != nil {
+			return nil, err
+		}
+		var m manifest
+		err = json.NewDecoder(resp.Body).Decode(&m)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(m.Manifests) == 0 {
+			return &m, nil
+		}
+		ref = m.Manifests[0].Digest
+		for _, d := range m.Manifests {
+			if d.Platform != nil && d.Platform.OS == `linux` && d.Platform.Architecture == runtime.GOARCH {
+				ref = d.Digest
+				break
+			}
+		}
+	}
+	return nil, errors.New("nested image indexes are not supported")
+}
+
+// memoryStore holds the files of an image, by path.
+type memoryStore map[string][]byte
+
+func (m memoryStore) list(prefix string) ([]objectInfo, error) {
+	return nil, errors.New("not listable")
+}
+
+func (m memoryStore) get(key string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(m[key])), nil
+}
+
+// applyLayer streams a layer, removing the files its whiteouts delete from
+// those of the layers below, and adding its regular files and the hard
+// links to them. Symbolic links, devices and the like are left out. The
+// blob must match its digest, or none of the layer is applied.
+func (r *registry) applyLayer(store memoryStore, layer descriptor) error {
+	if !strings.HasSuffix(layer.MediaType, `tar`) && !strings.HasSuffix(layer.MediaType, `gzip`) {
+		return errors.New("unsupported media type " + layer.MediaType)
+	}
+	if !strings.HasPrefix(layer.Digest, `sha256:`) {
+		return errors.New("unsupported digest " + layer.Digest)
+	}
+	resp, err := r.get(`blobs/`+layer.Digest, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	digest := sha256.New()
+	blob := io.TeeReader(resp.Body, digest)
+	var in io.Reader = blob
+	if strings.HasSuffix(layer.MediaType, `gzip`) {
+		zr, err := gzip.NewReader(blob)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		in = zr
+	}
+
+	removed := func(dir, name string) {
+		prefix := path.Join(dir, name)
+		for key := range store {
+			if key == prefix || strings.HasPrefix(key, prefix+`/`) || (name == `` && (dir == `` || strings.HasPrefix(key, dir+`/`))) {
+				delete(store, key)
+			}
+		}
+	}
+	added := make(map[string][]byte)
+	tr := tar.NewReader(in)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path.Clean(`/`+h.Name), `/`)
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, `/`)
+		switch {
+		case base == `.wh..wh..opq`:
+			/* An opaque directory hides everything below it in lower layers. */
+			removed(dir, ``)
+		case strings.HasPrefix(base, `.wh.`):
+			removed(dir, strings.TrimPrefix(base, `.wh.`))
+		case h.Typeflag == tar.TypeReg:
+			b, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			added[name] = b
+		case h.Typeflag == tar.TypeLink:
+			/* A hard link names a file earlier in this layer or in those below. */
+			target := strings.TrimPrefix(path.Clean(`/`+h.Linkname), `/`)
+			if b, ok := added[target]; ok {
+				added[name] = b
+			} else if b, ok := store[target]; ok {
+				added[name] = b
+			}
+		}
+	}
+	/* Read what the tar reader left, so the digest is of the whole blob. */
+	if _, err := io.Copy(ioutil.Discard, in); err != nil {
+		return err
+	}
+	if _, err := io.Copy(ioutil.Discard, blob); err != nil {
+		return err
+	}
+	if `sha256:`+hex.EncodeToString(digest.Sum(nil)) != layer.Digest {
+		return errors.New("blob does not match its digest")
+	}
+	for name, b := range added {
+		store[name] = b
+	}
+	return nil
+}
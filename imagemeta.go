@@ -0,0 +1,325 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
+
+// isPicture reports whether a file is an image whose metadata can be read.
+func isPicture(lower string) bool {
+	for _, ext := range []string{`.png`, `.jpg`, `.jpeg`, `.tif`, `.tiff`, `.webp`} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// pngKeywords are the text chunks of a PNG image which bear on its rights.
+var pngKeywords = map[string]bool{
+	`Author`:     true,
+	`Copyright`:  true,
+	`Disclaimer`: true,
+	`License`:    true,
+}
+
+// The namespaces of the XMP properties which bear on an image's rights.
+const (
+	nsDC        = `http://purl.org/dc/elements/1.1/`
+	nsXMPRights = `http://ns.adobe.com/xap/1.0/rights/`
+	nsCC        = `http://creativecommons.org/ns#`
+	nsRDF       = `http://www.w3.org/1999/02/22-rdf-syntax-ns#`
+)
+
+// xmpProperties are the XMP properties of an image's creator and rights.
+var xmpProperties = map[xml.Name]bool{
+	{Space: nsDC, Local: `creator`}:             true,
+	{Space: nsDC, Local: `rights`}:              true,
+	{Space: nsXMPRights, Local: `UsageTerms`}:   true,
+	{Space: nsXMPRights, Local: `WebStatement`}: true,
+	{Space: nsCC, Local: `attributionName`}:     true,
+	{Space: nsCC, Local: `license`}:             true,
+}
+
+// The EXIF tags of an image's creator and copyright, and of its XMP.
+const (
+	exifArtist    = 0x013b
+	exifXMP       = 0x02bc
+	exifCopyright = 0x8298
+)
+
+// imageMetadata gathers the fields of an image's metadata, once each.
+type imageMetadata struct {
+	text bytes.Buffer
+	seen map[string]bool
+}
+
+func (m *imageMetadata) add(s string) {
+	for _, line := range strings.Split(s, "\x00") {
+		line = strings.TrimSpace(line)
+		if line == `` || m.seen[line] {
+			continue
+		}
+		m.seen[line] = true
+		m.text.WriteString(line)
+		m.text.WriteString("\n")
+	}
+}
+
+// pictureText returns the creator, copyright and license of a PNG, JPEG,
+// TIFF or WebP image, from its text chunks, EXIF and XMP, one field per
+// line. It fails for anything that doesn't parse as an image, which is
+// then read as it stands.
+func pictureText(b []byte) ([]byte, bool) {
+	m := imageMetadata{seen: make(map[string]bool)}
+	var ok bool
+	switch {
+	case bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")):
+		ok = m.png(b[8:])
+	case bytes.HasPrefix(b, []byte("\xff\xd8")):
+		ok = m.jpeg(b[2:])
+	case bytes.HasPrefix(b, []byte("II*\x00")) || bytes.HasPrefix(b, []byte("MM\x00*")):
+		ok = m.exif(b)
+	case len(b) >= 12 && string(b[:4]) == `RIFF` && string(b[8:12]) == `WEBP`:
+		ok = m.webp(b[12:])
+	}
+	return m.text.Bytes(), ok
+}
+
+// png reads the tEXt, zTXt, iTXt and eXIf chunks of a PNG image.
+func (m *imageMetadata) png(b []byte) bool {
+	for len(b) >= 12 {
+		length := int(binary.BigEndian.Uint32(b))
+		kind := string(b[4:8])
+		if length < 0 || 12+length > len(b) {
+			return false
+		}
+		data := b[8 : 8+length]
+		b = b[12+length:]
+
+		if kind == `eXIf` {
+			m.exif(data)
+			continue
+		}
+		if kind != `tEXt` && kind != `zTXt` && kind != `iTXt` {
+			continue
+		}
+		nul := bytes.IndexByte(data, 0)
+		if nul < 0 {
+			continue
+		}
+		keyword, data := string(data[:nul]), data[nul+1:]
+		if !pngKeywords[keyword] && keyword != `XML:com.adobe.xmp` {
+			continue
+		}
+		var text []byte
+		switch kind {
+		case `tEXt`:
+			text = latin1(data)
+		case `zTXt`:
+			if len(data) < 1 {
+				continue
+			}
+			text = latin1(inflate(data[1:]))
+		case `iTXt`:
+			/* Compression flag and method, language and translated keyword. */
+			if len(data) < 2 {
+				continue
+			}
+			compressed := data[0] == 1
+			rest := bytes.SplitN(data[2:], []byte{0}, 3)
+			if len(rest) < 3 {
+				continue
+			}
+			text = rest[2]
+			if compressed {
+				text = inflate(text)
+			}
+		}
+		if keyword == `XML:com.adobe.xmp` {
+			m.xmp(text)
+		} else {
+			m.add(string(text))
+		}
+	}
+	return true
+}
+
+// jpeg reads the EXIF and XMP of the APP1 segments of a JPEG image, which
+// precede the image data.
+func (m *imageMetadata) jpeg(b []byte) bool {
+	for len(b) >= 4 && b[0] == 0xff {
+		marker := b[1]
+		if marker == 0xd9 || marker == 0xda {
+			break
+		}
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			b = b[2:]
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(b[2:]))
+		if length < 2 || 2+length > len(b) {
+			return false
+		}
+		data := b[4 : 2+length]
+		b = b[2+length:]
+		if marker != 0xe1 {
+			continue
+		}
+		if bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+			m.exif(data[6:])
+		} else if bytes.HasPrefix(data, []byte("http://ns.adobe.com/xap/1.0/\x00")) {
+			m.xmp(data[len("http://ns.adobe.com/xap/1.0/\x00"):])
+		}
+	}
+	return true
+}
+
+// webp reads the EXIF and XMP chunks of a WebP image.
+func (m *imageMetadata) webp(b []byte) bool {
+	for len(b) >= 8 {
+		kind := string(b[:4])
+		length := int(binary.LittleEndian.Uint32(b[4:]))
+		if length < 0 || 8+length > len(b) {
+			return false
+		}
+		data := b[8 : 8+length]
+		b = b[8+length:]
+		if length%2 == 1 && len(b) > 0 {
+			b = b[1:]
+		}
+		switch kind {
+		case `EXIF`:
+			m.exif(bytes.TrimPrefix(data, []byte("Exif\x00\x00")))
+		case `XMP `:
+			m.xmp(data)
+		}
+	}
+	return true
+}
+
+// exif reads the artist, copyright and XMP tags of the first directory of
+// EXIF data, which is laid out as a TIFF file is.
+func (m *imageMetadata) exif(b []byte) bool {
+	if len(b) < 8 {
+		return false
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case `II`:
+		order = binary.LittleEndian
+	case `MM`:
+		order = binary.BigEndian
+	default:
+		return false
+	}
+	ifd := int(order.Uint32(b[4:]))
+	if ifd < 0 || ifd+2 > len(b) {
+		return false
+	}
+	entries := int(order.Uint16(b[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(b) {
+			return false
+		}
+		tag := order.Uint16(b[entry:])
+		if tag != exifArtist && tag != exifCopyright && tag != exifXMP {
+			continue
+		}
+		/* A byte per count, held in the entry itself if they fit. */
+		count := int(order.Uint32(b[entry+4:]))
+		start := entry + 8
+		if count > 4 {
+			start = int(order.Uint32(b[entry+8:]))
+		}
+		if count < 0 || start < 0 || start+count > len(b) {
+			continue
+		}
+		value := b[start : start+count]
+		if tag == exifXMP {
+			m.xmp(value)
+		} else {
+			m.add(string(latin1(value)))
+		}
+	}
+	return true
+}
+
+// xmp reads the creator and rights properties of an XMP packet, whether
+// given as elements or as attributes, and the licenses they link to.
+func (m *imageMetadata) xmp(b []byte) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.Strict = false
+	inside := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if xmpProperties[t.Name] {
+				inside++
+			}
+			for _, attr := range t.Attr {
+				if xmpProperties[attr.Name] || (inside > 0 && attr.Name == xml.Name{Space: nsRDF, Local: `resource`}) {
+					m.add(attr.Value)
+				}
+			}
+		case xml.EndElement:
+			if xmpProperties[t.Name] {
+				inside--
+			}
+		case xml.CharData:
+			if inside > 0 {
+				m.add(string(t))
+			}
+		}
+	}
+}
+
+// latin1 decodes the Latin-1 text of PNG chunks and EXIF tags, unless it
+// is valid UTF-8, as it often is all the same.
+func latin1(b []byte) []byte {
+	if utf8.Valid(b) {
+		return b
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return []byte(string(runes))
+}
+
+// inflate decompresses zlib data, yielding nothing if it's corrupt.
+func inflate(b []byte) []byte {
+	zr, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	text, _ := ioutil.ReadAll(zr)
+	return text
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// errInterrupted ends the walk once weasel is interrupted.
+var errInterrupted = errors.New("interrupted")
+
+var interruptions int32
+
+// trapInterrupts stops the scan at the first SIGINT or SIGTERM, rather than
+// weasel dying with nothing to show for it: no more files are begun, those
+// begun are finished, and a partial report is made of them. A second
+// signal kills weasel as usual.
+func trapInterrupts() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ch
+		atomic.StoreInt32(&interruptions, 1)
+		signal.Stop(ch)
+	}()
+}
+
+// interrupted tells whether weasel was interrupted, so the rest of the
+// scan is abandoned.
+func interrupted() bool {
+	return atomic.LoadInt32(&interruptions) != 0
+}
@@ -17,56 +17,179 @@ limitations under the License.
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime/pprof"
-	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 func main() {
+	serveJS()
+
 	quiet := true
 	cd := ``
 	argDone := false
-	nextFile := false
 	logFile := ``
-	nextSubdir := false
 	profile := false
 	subdir := ``
-	for _, arg := range os.Args[1:] {
-		if nextFile {
-			nextFile = false
-			logFile = arg
-			continue
+	primary := ``
+	maxUnknownArg := ``
+	maxUnknownPctArg := ``
+	timeoutArg := ``
+	maxOpenArg := ``
+	maxErrorsArg := ``
+	columnsArg := ``
+	shardArg := ``
+	debugFile := ``
+	filesFrom := ``
+	nulSeparated := false
+	tracked := false
+	args := os.Args[1:]
+	command := ``
+	if len(args) > 0 && (args[0] == `compat` || args[0] == `history` || args[0] == `blame` || args[0] == `schema` || args[0] == `identify` || args[0] == `check` || args[0] == `merge` || args[0] == `update-licenses` || args[0] == `gen-fixture` || args[0] == `config` || args[0] == `attributions` || args[0] == `binary-license` || args[0] == `audit` || args[0] == `baseline` || args[0] == `licenses` || args[0] == `explain` || args[0] == `triage` || args[0] == `lsp` || args[0] == `enrich` || args[0] == `verify` || args[0] == `go-packages`) {
+		command = args[0]
+		args = args[1:]
+	}
+
+	/* Arguments which take a value, either as the next argument or after an `=`. */
+	values := map[string]*string{
+		`-f`:                 &logFile,
+		`-d`:                 &subdir,
+		`--files-from`:       &filesFrom,
+		`--max-unknown`:      &maxUnknownArg,
+		`--max-unknown-pct`:  &maxUnknownPctArg,
+		`--vendored`:         &vendorPolicy,
+		`--db`:               &dbFile,
+		`--notify-url`:       &notifyURL,
+		`--format`:           &outputFormat,
+		`--file-timeout`:     &timeoutArg,
+		`--max-open-files`:   &maxOpenArg,
+		`--max-errors`:       &maxErrorsArg,
+		`--sort`:             &sortBy,
+		`--columns`:          &columnsArg,
+		`--report`:           &reportKind,
+		`--shard`:            &shardArg,
+		`--paths`:            &pathStyle,
+		`--attestation`:      &attestationFile,
+		`--strip-prefix`:     &stripPrefix,
+		`--path-prefix`:      &pathPrefix,
+		`--debug-tokens`:     &debugFile,
+		`--metrics`:          &metricsFile,
+		`--extract-licenses`: &extractDir,
+		`--baseline`:         &baselineFile,
+		`--checkpoint`:       &checkpointFile,
+	}
+	if command == `compat` {
+		values[`--primary`] = &primary
+	}
+	if command == `merge` {
+		values[`-o`] = &mergeOutput
+	}
+	outputFile := ``
+	if command == `attributions` || command == `binary-license` || command == `baseline` || command == `enrich` {
+		values[`-o`] = &outputFile
+	}
+	var sbom *spdxDocument
+	if command == `enrich` {
+		values[`--in`] = &sbomInput
+	}
+	if command == `verify` {
+		values[`--against`] = &sbomInput
+	}
+	if command == `update-licenses` {
+		values[`--url`] = &spdxListURL
+	}
+	fixtureLicense := ``
+	fixtureStyle := `go`
+	if command == `gen-fixture` {
+		values[`--license`] = &fixtureLicense
+		values[`--style`] = &fixtureStyle
+	}
+	lastArg := `10`
+	if command == `history` {
+		values[`--last`] = &lastArg
+	}
+
+	/* Arguments which take no value. `-a` clears `-q`. */
+	switches := map[string]*bool{
+		`-q`:                 &quiet,
+		`-p`:                 &profile,
+		`-0`:                 &nulSeparated,
+		`--tracked`:          &tracked,
+		`--spdx-ids`:         &useSPDX,
+		`--conclusion`:       &printConclusion,
+		`--explain`:          &explain,
+		`--offline`:          &offline,
+		`--github-check`:     &githubCheck,
+		`--fix`:              &fix,
+		`--fail-fast`:        &failFast,
+		`--interactive`:      &interactive,
+		`--reverse`:          &reverseSort,
+		`--reproducible`:     &reproducible,
+		`--sign-attestation`: &signAttestation,
+	}
+
+	/* Where each value came from, for `weasel config show`. */
+	sources := make(map[string]string)
+	for name, v := range values {
+		if env := envName(name); env != `` {
+			if value, ok := os.LookupEnv(env); ok {
+				*v = value
+				sources[name] = env
+			}
+		}
+	}
+	for name, v := range switches {
+		if env := envName(name); env != `` {
+			if value, ok := os.LookupEnv(env); ok {
+				b, err := strconv.ParseBool(value)
+				if err != nil {
+					fmt.Println("Invalid " + env + ": `" + value + "`!")
+					os.Exit(1)
+					return
+				}
+				*v = b
+				sources[name] = env
+			}
 		}
-		if nextSubdir {
-			nextSubdir = false
-			subdir = arg
+	}
+
+	var operands []string
+	var moreRoots []string
+	var next *string
+	for _, arg := range args {
+		if next != nil {
+			*next = arg
+			next = nil
 			continue
 		}
 		if !argDone {
-			if arg == `-q` {
-				quiet = true
-				continue
-			}
-			if arg == `-a` {
-				quiet = false
+			if v, ok := values[arg]; ok {
+				next = v
+				sources[arg] = `flag`
 				continue
 			}
-			if arg == `-f` {
-				nextFile = true
-				continue
+			if parts := strings.SplitN(arg, `=`, 2); len(parts) == 2 && strings.HasPrefix(arg, `--`) {
+				if v, ok := values[parts[0]]; ok {
+					*v = parts[1]
+					sources[parts[0]] = `flag`
+					continue
+				}
 			}
-			if arg == `-d` {
-				nextSubdir = true
+			if v, ok := switches[arg]; ok {
+				*v = true
+				sources[arg] = `flag`
 				continue
 			}
-			if arg == `-p` {
-				profile = true
+			if arg == `-a` {
+				quiet = false
+				sources[`-q`] = `flag`
 				continue
 			}
 			if arg == `--` {
@@ -74,14 +197,166 @@ func main() {
 				continue
 			}
 		}
+		if command == `blame` || command == `identify` || command == `check` || command == `merge` || command == `config` || command == `explain` {
+			operands = append(operands, arg)
+			continue
+		}
 		if cd == `` {
 			cd = arg
 			continue
 		}
+		if command == `` || command == `compat` {
+			moreRoots = append(moreRoots, arg)
+			continue
+		}
 		fmt.Println("Unknown argument: `" + arg + "`!")
 		os.Exit(1)
 		return
 	}
+	primaryLicense = License(primary)
+
+	if offline {
+		if use := networkUse(command, cd); use != `` {
+			fmt.Println("Cannot use " + use + " with --offline!")
+			os.Exit(1)
+			return
+		}
+	}
+
+	if command == `schema` {
+		fmt.Print(jsonSchema)
+		os.Exit(0)
+	}
+	if command == `identify` {
+		if err := loadCustomLicenses(identifyConfig(operands)); err != nil {
+			fmt.Println("Unable to load custom licenses: " + err.Error())
+			os.Exit(1)
+		}
+		os.Exit(identify(os.Stdout, operands))
+	}
+	if command == `gen-fixture` {
+		if fixtureLicense == `` {
+			fmt.Println("No --license given for the fixture!")
+			os.Exit(1)
+		}
+		if err := loadCustomLicenses(nearestConfig(`.`)); err != nil {
+			fmt.Println("Unable to load custom licenses: " + err.Error())
+			os.Exit(1)
+		}
+		if err := genFixture(os.Stdout, License(fixtureLicense), fixtureStyle); err != nil {
+			fmt.Println("Unable to generate fixture: " + err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if debugFile != `` {
+		if err := debugTokens(os.Stdout, debugFile); err != nil {
+			fmt.Println("Unable to read " + debugFile + ": " + err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if command == `update-licenses` {
+		if err := updateLicenses(os.Stdout); err != nil {
+			fmt.Println("Unable to update licenses: " + err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	maxUnknown := -1
+	if maxUnknownArg != `` {
+		n, err := strconv.Atoi(maxUnknownArg)
+		if err != nil || n < 0 {
+			fmt.Println("Invalid --max-unknown: `" + maxUnknownArg + "`!")
+			os.Exit(1)
+			return
+		}
+		maxUnknown = n
+	}
+	maxUnknownPct := -1.0
+	if maxUnknownPctArg != `` {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(maxUnknownPctArg, `%`), 64)
+		if err != nil || pct < 0 {
+			fmt.Println("Invalid --max-unknown-pct: `" + maxUnknownPctArg + "`!")
+			os.Exit(1)
+			return
+		}
+		maxUnknownPct = pct
+	}
+	if maxErrorsArg != `` {
+		n, err := strconv.Atoi(maxErrorsArg)
+		if err != nil || n < 0 {
+			fmt.Println("Invalid --max-errors: `" + maxErrorsArg + "`!")
+			os.Exit(1)
+			return
+		}
+		maxErrors = n
+	}
+	if timeoutArg != `` {
+		d, err := time.ParseDuration(timeoutArg)
+		if err != nil || d < 0 {
+			fmt.Println("Invalid --file-timeout: `" + timeoutArg + "`!")
+			os.Exit(1)
+			return
+		}
+		fileTimeout = d
+	}
+	if maxOpenArg != `` {
+		n, err := strconv.Atoi(maxOpenArg)
+		if err != nil || n <= 0 {
+			fmt.Println("Invalid --max-open-files: `" + maxOpenArg + "`!")
+			os.Exit(1)
+			return
+		}
+		openFiles = make(chan struct{}, n)
+	}
+	if outputFormat != `text` && outputFormat != `json` && outputFormat != `ndjson` && outputFormat != `azdo` && outputFormat != `teamcity` && outputFormat != `tap` && outputFormat != `compact` {
+		fmt.Println("Invalid --format, expected `text`, `json`, `ndjson`, `azdo`, `teamcity`, `tap` or `compact`: `" + outputFormat + "`!")
+		os.Exit(1)
+		return
+	}
+	if outputFormat != `text` {
+		explain = true
+	}
+	digests = outputFormat == `json` || outputFormat == `ndjson` || mergeOutput != `` || attestationFile != ``
+	if columnsArg != `` {
+		cols, ok := parseColumns(columnsArg)
+		if !ok {
+			fmt.Println("Invalid --columns, expected a list of `status`, `licenses`, `confidence`, `path` and `size`: `" + columnsArg + "`!")
+			os.Exit(1)
+			return
+		}
+		textColumns = cols
+	}
+	if sortBy != `path` && sortBy != `license` && sortBy != `status` {
+		fmt.Println("Invalid --sort, expected `path`, `license` or `status`: `" + sortBy + "`!")
+		os.Exit(1)
+		return
+	}
+	if shardArg != `` {
+		var ok bool
+		if shardIndex, shardCount, ok = parseShard(shardArg); !ok {
+			fmt.Println("Invalid --shard, expected `<i>/<n>` such as `1/4`: `" + shardArg + "`!")
+			os.Exit(1)
+			return
+		}
+	}
+	if reportKind != `files` && reportKind != `heatmap` {
+		fmt.Println("Invalid --report, expected `files` or `heatmap`: `" + reportKind + "`!")
+		os.Exit(1)
+		return
+	}
+	if pathStyle != `relative` && pathStyle != `absolute` && pathStyle != `from-cwd` {
+		fmt.Println("Invalid --paths, expected `relative`, `absolute` or `from-cwd`: `" + pathStyle + "`!")
+		os.Exit(1)
+		return
+	}
+	if vendorPolicy != `document` && vendorPolicy != `report` {
+		fmt.Println("Invalid --vendored, expected `document` or `report`: `" + vendorPolicy + "`!")
+		os.Exit(1)
+		return
+	}
 
 	if profile {
 		pf, err := os.Create("weasel.pprof")
@@ -128,9 +403,167 @@ func main() {
 		w = io.MultiWriter(os.Stdout, f)
 	}
 
+	if dbFile != `` {
+		var err error
+		dbFile, err = filepath.Abs(dbFile)
+		if err != nil {
+			fmt.Fprintln(w, "Unable to get absolute path for --db: "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+	if metricsFile != `` {
+		var err error
+		metricsFile, err = filepath.Abs(metricsFile)
+		if err != nil {
+			fmt.Fprintln(w, "Unable to get absolute path for --metrics: "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+
+	if command == `blame` || command == `merge` {
+		for i, operand := range operands {
+			var err error
+			operands[i], err = filepath.Abs(operand)
+			if err != nil {
+				fmt.Fprintln(w, "Unable to get absolute path for "+operand+": "+err.Error())
+				os.Exit(1)
+				return
+			}
+		}
+	}
+
+	for _, output := range []*string{&mergeOutput, &outputFile, &extractDir, &baselineFile, &sbomInput, &attestationFile, &checkpointFile} {
+		if *output != `` {
+			var err error
+			*output, err = filepath.Abs(*output)
+			if err != nil {
+				fmt.Fprintln(w, "Unable to get absolute path for "+*output+": "+err.Error())
+				os.Exit(1)
+				return
+			}
+		}
+	}
+
+	/* The listed files are relative to the directory weasel was run from. */
+	var listed []string
+	if filesFrom != `` && command != `config` {
+		if command != `` && command != `check` {
+			fmt.Fprintln(w, "Cannot use --files-from with `weasel "+command+"`!")
+			os.Exit(1)
+			return
+		}
+		if len(moreRoots) > 0 || isObjectURL(cd) || isImageURL(cd) {
+			fmt.Fprintln(w, "Cannot use --files-from with several targets or "+cd+"!")
+			os.Exit(1)
+			return
+		}
+		if tracked {
+			fmt.Fprintln(w, "Cannot use --files-from with --tracked!")
+			os.Exit(1)
+			return
+		}
+		var err error
+		if listed, err = readFileList(filesFrom, nulSeparated); err != nil {
+			fmt.Fprintln(w, "Cannot read --files-from: "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+
+	if tracked && (command == `check` || command == `explain`) {
+		fmt.Fprintln(w, "Cannot use --tracked with `weasel "+command+"`!")
+		os.Exit(1)
+		return
+	}
+	if tracked && (len(moreRoots) > 0 || isObjectURL(cd) || isImageURL(cd)) {
+		fmt.Fprintln(w, "Cannot use --tracked with several targets or "+cd+"!")
+		os.Exit(1)
+		return
+	}
+
+	objects := isObjectURL(cd) || isImageURL(cd)
+	if objects && (len(moreRoots) > 0 || subdir != ``) {
+		fmt.Fprintln(w, "Cannot use -d or several targets with "+cd+"!")
+		os.Exit(1)
+		return
+	}
+	if fix && command != `` {
+		fmt.Fprintln(w, "Cannot use --fix with `weasel "+command+"`!")
+		os.Exit(1)
+		return
+	}
+	if fix && (len(moreRoots) > 0 || objects) {
+		fmt.Fprintln(w, "Cannot use --fix with several targets or "+cd+"!")
+		os.Exit(1)
+		return
+	}
+	if command == `go-packages` && outputFormat != `text` && outputFormat != `json` {
+		fmt.Fprintln(w, "Cannot use --format "+outputFormat+" with `weasel go-packages`!")
+		os.Exit(1)
+		return
+	}
+	if signAttestation && attestationFile == `` {
+		fmt.Fprintln(w, "Cannot use --sign-attestation without --attestation!")
+		os.Exit(1)
+		return
+	}
+	if interactive && !fix {
+		fmt.Fprintln(w, "Cannot use --interactive without --fix!")
+		os.Exit(1)
+		return
+	}
+	if objects && command == `triage` {
+		fmt.Fprintln(w, "Cannot triage "+cd+", which has no .dependency_license to write!")
+		os.Exit(1)
+		return
+	}
+	if command == `enrich` || command == `verify` {
+		if sbomInput == `` && command == `enrich` {
+			fmt.Fprintln(w, "Expected `weasel enrich --in <sbom.spdx.json>`!")
+			os.Exit(1)
+			return
+		}
+		if sbomInput == `` {
+			fmt.Fprintln(w, "Expected `weasel verify --against <sbom.spdx.json>`!")
+			os.Exit(1)
+			return
+		}
+		var err error
+		if sbom, err = readSPDXDocument(sbomInput); err != nil {
+			fmt.Fprintln(w, "Cannot read SBOM: "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+
+	/* Several target directories are scanned as separate projects. */
+	var projects, prefixes []string
+	if len(moreRoots) > 0 {
+		if subdir != `` {
+			fmt.Fprintln(w, "Cannot use -d with several target directories!")
+			os.Exit(1)
+			return
+		}
+		for _, root := range append([]string{cd}, moreRoots...) {
+			abs, err := filepath.Abs(stripLongPath(root))
+			if err != nil {
+				fmt.Fprintln(w, "Unable to get absolute directory for "+root+": "+err.Error())
+				os.Exit(1)
+				return
+			}
+			projects = append(projects, abs)
+			prefixes = append(prefixes, filepath.Clean(root))
+		}
+	}
+
+	if !objects {
+		cd = stripLongPath(cd)
+	}
 	if subdir != `` {
 		var err error
-		subdir, err = filepath.Abs(subdir)
+		subdir, err = filepath.Abs(stripLongPath(subdir))
 		if err != nil {
 			fmt.Fprintln(w, "Unable to get absolute directory for -d: "+err.Error())
 			os.Exit(1)
@@ -145,28 +578,53 @@ func main() {
 			fmt.Fprintln(w, "Unable to get working directory: "+err.Error())
 			return
 		}
-		p = strings.TrimRight(p, `/`)
+		p = filepath.Clean(p)
 
 		patience := 10000 /* patience exists in case there are loops or other excessively long paths. */
-		for p != `` && patience != 0 {
+		for patience != 0 {
 			if fi, err := os.Stat(filepath.Join(p, ".git")); err == nil && fi.IsDir() {
 				cd = p
 				break
 			}
-			p, _ = filepath.Split(p)
-			p = strings.TrimRight(p, `/`)
+			parent := filepath.Dir(p) /* Dir, unlike splitting on `/`, stops at C:\ or \\server\share. */
+			if parent == p {
+				break
+			}
+			p = parent
 
 			patience--
 		}
 	}
-	if !quiet {
-		fmt.Fprintln(w, "In directory: "+cd)
+	if !quiet && outputFormat == `text` && !reproducible {
+		if len(prefixes) > 0 {
+			fmt.Fprintln(w, "In directories: "+strings.Join(prefixes, `, `))
+		} else {
+			fmt.Fprintln(w, "In directory: "+cd)
+		}
 	}
-	err := os.Chdir(cd)
-	if err != nil {
+	invokedFrom, _ = os.Getwd()
+	var err error
+	if objects {
+		/* The bucket or image is scanned in place of the working directory. */
+		if isImageURL(cd) {
+			sourceFS, err = newImageFS(cd)
+		} else {
+			sourceFS, err = newObjectFS(cd)
+		}
+		if err != nil {
+			fmt.Fprintln(w, "Cannot list "+cd+": "+err.Error())
+			os.Exit(1)
+			return
+		}
+	} else if err = os.Chdir(cd); err != nil {
 		fmt.Fprintln(w, "Failed to enter target directory: "+err.Error()+"!")
 		os.Exit(1)
 		return
+	} else if len(projects) > 0 {
+		/* The paths of several projects are prefixed with their targets. */
+		pathBase = invokedFrom
+	} else {
+		pathBase, _ = os.Getwd()
 	}
 
 	if subdir == `` {
@@ -186,96 +644,608 @@ func main() {
 		}
 	}
 
-	loadOverrides()
-	recordDocumentedLicenses()
-
-	files := make(map[string][]License)
-	var wg sync.WaitGroup
-	var filesLock sync.Mutex
-	err = filepath.Walk(subdir, func(name string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	if command == `config` {
+		if len(operands) == 1 && operands[0] == `validate` {
+			os.Exit(validateConfigs(w))
 		}
-
-		if filepath.Base(name) == `.git` {
-			return filepath.SkipDir
+		if len(operands) >= 1 && operands[0] == `show` && (len(operands) == 1 || (len(operands) == 2 && operands[1] == `--effective`)) {
+			var settings []setting
+			for name, v := range values {
+				source := sources[name]
+				if source == `` {
+					source = `default`
+				}
+				settings = append(settings, setting{name, *v, source})
+			}
+			for name, v := range switches {
+				source := sources[name]
+				if source == `` {
+					source = `default`
+				}
+				settings = append(settings, setting{name, strconv.FormatBool(*v), source})
+			}
+			showConfig(w, settings, len(operands) == 2)
+			os.Exit(0)
 		}
+		fmt.Fprintln(w, "Expected `weasel config validate` or `weasel config show [--effective]`!")
+		os.Exit(1)
+		return
+	}
 
-		if Ignored(name) {
-			return nil
-		}
+	if err := loadProjectConfig(); err != nil {
+		fmt.Fprintln(w, "Unable to load custom licenses: "+err.Error())
+		os.Exit(1)
+		return
+	}
 
-		if info.IsDir() {
-			return nil
-		}
+	if command == `licenses` {
+		os.Exit(listLicenses(w, outputFormat))
+	}
 
-		if (info.Mode() & os.ModeSymlink) != 0 {
-			return nil
-		}
+	if command == `audit` {
+		loadOverrides()
+		os.Exit(audit(w))
+	}
 
-		if info.Size() == 0 {
-			filesLock.Lock()
-			defer filesLock.Unlock()
-			files[name] = append(files[name], License("Empty"))
-			return nil
-		}
+	if command == `lsp` {
+		os.Exit(serveLSP(os.Stdin, os.Stdout))
+	}
 
-		wg.Add(1)
-		go func(name string) {
-			defer wg.Done()
-			licenses, err := fileLicenses(name)
+	if command == `blame` {
+		if len(operands) == 0 {
+			fmt.Fprintln(w, "No files given to blame!")
+			os.Exit(1)
+			return
+		}
+		cur, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintln(w, "Failed to get working dir: "+err.Error())
+			os.Exit(1)
+			return
+		}
+		failed := false
+		for _, operand := range operands {
+			name, err := filepath.Rel(cur, operand)
+			if err == nil {
+				err = blame(w, name)
+			}
 			if err != nil {
-				licenses = []License{License("Error: " + err.Error() + "!")}
+				fmt.Fprintln(w, "Cannot blame "+operand+": "+err.Error())
+				failed = true
 			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
-			filesLock.Lock()
-			defer filesLock.Unlock()
-			files[name] = append(files[name], override[name]...)
-			files[name] = append(files[name], licenses...)
-			files[name] = Collide(Uniq(files[name]))
-		}(name)
-		return nil
-	})
-	wg.Wait()
-	if err != nil {
-		fmt.Fprintln(w, err)
+	if command == `history` {
+		last, err := strconv.Atoi(lastArg)
+		if err != nil || last <= 0 {
+			fmt.Fprintln(w, "Invalid --last: `"+lastArg+"`!")
+			os.Exit(1)
+			return
+		}
+		if err := history(w, last); err != nil {
+			fmt.Fprintln(w, "Cannot compile history: "+err.Error())
+			os.Exit(1)
+			return
+		}
+		if err := exportSpans(); err != nil {
+			fmt.Fprintln(w, "Cannot export traces to "+tracesURL+": "+err.Error())
+		}
+		os.Exit(0)
+	}
+
+	if command == `explain` {
+		if len(operands) != 1 {
+			fmt.Fprintln(w, "Expected `weasel explain <file>` or `weasel explain <code>`!")
+			os.Exit(1)
+			return
+		}
+		if isCode(operands[0]) {
+			os.Exit(explainCode(w, operands[0]))
+		}
+		explain = true
+	}
+
+	roots := []string{subdir}
+	if command == `check` || command == `explain` || filesFrom != `` {
+		if len(operands) == 0 && filesFrom == `` {
+			fmt.Fprintln(w, "No files given to check!")
+			os.Exit(1)
+			return
+		}
+		cur, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintln(w, "Failed to get working dir: "+err.Error())
+			os.Exit(1)
+			return
+		}
+		roots = nil
+		for _, operand := range operands {
+			/* Relative paths are from the project root, not the current directory. */
+			if filepath.IsAbs(operand) {
+				if operand, err = filepath.Rel(cur, operand); err != nil {
+					fmt.Fprintln(w, "Failed to get relative path: "+err.Error())
+					os.Exit(1)
+					return
+				}
+			}
+			roots = append(roots, filepath.Clean(operand))
+		}
+		for _, name := range listed {
+			rel, err := filepath.Rel(cur, name)
+			if err != nil {
+				fmt.Fprintln(w, "Failed to get relative path: "+err.Error())
+				os.Exit(1)
+				return
+			}
+			roots = append(roots, rel)
+		}
+	}
+	/* Only the named files are scanned, so the whole tree isn't judged. */
+	partial := command == `check` || command == `explain` || filesFrom != `` || shardCount > 0
+	if tracked {
+		var err error
+		if roots, err = trackedFiles(subdir); err != nil {
+			fmt.Fprintln(w, err)
+			os.Exit(1)
+			return
+		}
+	}
+
+	if err := startCheckpoint(); err != nil {
+		fmt.Fprintln(w, "Cannot resume from checkpoint: "+err.Error())
+		os.Exit(1)
+		return
+	}
+	trapInterrupts()
+	started := time.Now()
+	var files map[string][]License
+	var extras []string
+	var stale []Suppression
+	if len(projects) > 0 {
+		files, extras, stale, err = scanProjects(projects, prefixes)
+	} else if command == `merge` {
+		if len(operands) == 0 {
+			fmt.Fprintln(w, "No reports given to merge!")
+			os.Exit(1)
+			return
+		}
+		recordDocumentedLicenses()
+		files, extras, err = merge(operands)
+	} else {
+		loadOverrides()
+		recordDocumentedLicenses()
+		files, err = scan(roots...)
+		if err == nil && !partial {
+			/* Unused LICENSE entries concern the whole tree, not just the files checked. */
+			extras = documented.Extra()
+		}
+		/* Untracked files aren't walked, so ignore patterns for them would seem stale. */
+		if err == nil && !partial && !tracked && filepath.Clean(subdir) == `.` {
+			stale = staleEntries()
+		}
+	}
+	finishCheckpoint(err == nil && !interrupted())
+	if err == nil && interrupted() {
+		/* What concerns the whole tree cannot be judged from some of it. */
+		extras, stale = nil, nil
+		if fix || command == `explain` || command == `triage` || command == `compat` || command == `enrich` || command == `verify` || command == `go-packages` || command == `attributions` || command == `binary-license` || command == `baseline` {
+			fmt.Fprintln(w, "Interrupted before the scan was complete.")
+			os.Exit(exitInterrupted)
+		}
+	}
+	if err == errFailedFast {
+		name, lics := failure()
+		licStr, _, _ := describe(lics)
+		row := fileRow("Error", withCodes(licStr, codesOf(reported(lics))), displayPath(name), name)
+		writeRow(w, row, widths([]textRow{row}))
+		fmt.Fprintln(w, "Stopped at the first error, for --fail-fast.")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(w, err)
 		return
 	}
 
-forUnknownFiles:
-	for name, licenses := range files {
-		if len(licenses) == 0 {
-			parts := strings.Split(name, `/`)
-			for i := len(parts) - 1; i > 0; i-- {
-				for _, licName := range []string{`LICENSE`, `LICENCE`, `LICENSE.md`, `LICENCE.md`, `LICENSE.txt`, `LICENCE.txt`} {
-					licPath := strings.Join(parts[:i], `/`) + `/` + licName
-					if len(files[licPath]) != 0 {
-						for _, license := range files[licPath] {
-							if license != License(`Docs`) {
-								files[name] = append(files[name], License(string(license)+"~"))
-							}
-						}
-						continue forUnknownFiles
-					}
+	if baselineFile != `` && command != `baseline` {
+		if err := applyBaseline(baselineFile, files); err != nil {
+			fmt.Fprintln(w, "Cannot apply baseline: "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+
+	if command == `explain` {
+		os.Exit(explainFile(w, roots[0], files))
+	}
+
+	if command == `triage` {
+		os.Exit(triage(os.Stdin, w, files))
+	}
+
+	if fix {
+		os.Exit(fixHeaders(os.Stdin, w, files))
+	}
+
+	if command == `compat` {
+		if compat(w, files) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if command == `enrich` {
+		if err := enrichSBOM(w, os.Stderr, sbom, outputFile, files); err != nil {
+			fmt.Fprintln(w, "Cannot enrich "+sbomInput+": "+err.Error())
+			os.Exit(1)
+			return
+		}
+		os.Exit(0)
+	}
+
+	if command == `verify` {
+		os.Exit(verifySBOM(w, sbom, files))
+	}
+
+	if command == `go-packages` {
+		os.Exit(writeGoPackages(w, files, outputFormat))
+	}
+
+	if command == `attributions` || command == `binary-license` || command == `baseline` {
+		write := writeAttributions
+		switch command {
+		case `binary-license`:
+			write = writeBinaryLicense
+		case `baseline`:
+			write = writeBaseline
+		}
+		if outputFile == `` {
+			err = write(w, files)
+		} else {
+			var f *os.File
+			if f, err = os.Create(outputFile); err == nil {
+				err = write(f, files)
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
 				}
 			}
 		}
+		if err != nil {
+			fmt.Fprintln(w, "Cannot write "+command+": "+err.Error())
+			os.Exit(1)
+			return
+		}
+		os.Exit(0)
+	}
+
+	var filenames []string
+	for filename := range files {
+		filenames = append(filenames, filename)
+	}
+	sortFilenames(filenames, files)
+
+	failed := false
+	unknown := 0
+	total := 0
+	var violations []violation
+	var results []FileResult
+	rep := newReporter(w, files, quiet)
+	root, _ := os.Getwd()
+	if objects {
+		root = cd
+	} else if reproducible {
+		root = `.`
+	}
+	if err := rep.Start(root); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot write report: "+err.Error())
+		os.Exit(1)
+		return
+	}
+	for _, filename := range filenames {
+		licStr, ignore, undoc := describe(files[filename])
+		if !ignore {
+			res := FileResult{displayPath(filename), reported(files[filename]), undoc, codesOf(reported(files[filename])), evidenceFor(filename), suppressionsFor(filename), reportDigest(filename), filename}
+			results = append(results, res)
+			total++
+			if undoc {
+				violations = append(violations, violation{filename, licStr, res.Codes})
+				if strings.HasPrefix(licStr, `Unknown`) {
+					unknown++
+				} else if !isReadError(licStr) {
+					failed = true
+				}
+			}
+			if err := rep.Result(res); err != nil {
+				fmt.Fprintln(os.Stderr, "Cannot write report: "+err.Error())
+				os.Exit(1)
+				return
+			}
+		}
+	}
+	for _, extra := range extras {
+		violations = append(violations, violation{extra, "Extra-License!", []string{`WSL003`}})
+		failed = true
+	}
+	for _, s := range stale {
+		violations = append(violations, violation{s.Source + `: ` + s.Entry, staleFinding(s), []string{s.Code}})
+		failed = true
+	}
+	unreadable := len(unreadableErrors())
+	allowed := true
+	if unknown > 0 {
+		pct := 100 * float64(unknown) / float64(total)
+		if (maxUnknown < 0 && maxUnknownPct < 0) || (maxUnknown >= 0 && unknown > maxUnknown) || (maxUnknownPct >= 0 && pct > maxUnknownPct) {
+			failed = true
+			allowed = false
+		}
+	}
+	r := newReport(results, extras, Conclude(files), failed)
+	r.Root = root
+	r.Unreadable = unreadable
+	r.Ignored = ignoredPaths()
+	r.Suppressed = suppressionStats()
+	r.Stale = stale
+	r.Interrupted = interrupted()
+	if useSPDX {
+		r.Deprecated = deprecatedIDs(results)
+	}
+	if err := rep.Summary(Summary{r, unknown, total, allowed}); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot write report: "+err.Error())
+		os.Exit(1)
+		return
+	}
+	if mergeOutput != `` {
+		f, err := os.Create(mergeOutput)
+		if err == nil {
+			err = writeJSON(f, r)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot write merged report: "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+
+	if attestationFile != `` {
+		if err := writeAttestation(r); err != nil {
+			fmt.Fprintln(w, "Cannot write attestation to "+attestationFile+": "+err.Error())
+			os.Exit(1)
+			return
+		}
+		if signAttestation {
+			if err := signStatement(); err != nil {
+				fmt.Fprintln(w, "Cannot sign attestation: "+err.Error())
+				os.Exit(1)
+				return
+			}
+		}
+	}
+
+	if extractDir != `` {
+		if err := extractLicenses(w, extractDir, files); err != nil {
+			fmt.Fprintln(w, "Cannot extract licenses to "+extractDir+": "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+
+	/* A partial run would seem to have lost files in the history. */
+	if dbFile != `` && !r.Interrupted {
+		if err := recordRun(dbFile, started, files, failed); err != nil {
+			fmt.Fprintln(w, "Cannot record results in "+dbFile+": "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+
+	if metricsFile != `` {
+		if err := saveMetrics(files, time.Since(started), failed); err != nil {
+			fmt.Fprintln(w, "Cannot write metrics to "+metricsFile+": "+err.Error())
+			os.Exit(1)
+			return
+		}
+	}
+
+	if failed && notifyURL != `` {
+		if err := notify(notifyURL, violations); err != nil {
+			fmt.Fprintln(w, "Cannot notify "+notifyURL+": "+err.Error())
+		}
+	}
+
+	if githubCheck {
+		if err := postCheck(violations, r.Conclusion, exitCode(failed, unreadable > 0, r.Interrupted)); err != nil {
+			fmt.Fprintln(w, "Cannot post GitHub check: "+err.Error())
+		}
+	}
+
+	if err := exportSpans(); err != nil {
+		fmt.Fprintln(w, "Cannot export traces to "+tracesURL+": "+err.Error())
+	}
+
+	if profile {
+		pprof.StopCPUProfile()
+	}
+	os.Exit(exitCode(failed, unreadable > 0, r.Interrupted))
+}
+
+// exitInterrupted is added to the exit code when the report is partial.
+const exitInterrupted = 4
+
+// exitCode combines the classes of failure: 1 when licenses fail the
+// checks, 2 when files could not be read, and 4 when the scan was
+// interrupted.
+func exitCode(failed, unreadable, interrupted bool) int {
+	code := 0
+	if failed {
+		code |= 1
+	}
+	if unreadable {
+		code |= 2
+	}
+	if interrupted {
+		code |= exitInterrupted
+	}
+	return code
+}
+
+// reported returns a file's licenses as they appear in the report.
+func reported(lics []License) []License {
+	if len(lics) == 0 {
+		return []License{License(`Unknown!`)}
+	}
+	if !useSPDX {
+		return lics
+	}
+	spdxLics := make([]License, len(lics))
+	for i, lic := range lics {
+		spdxLics[i] = lic.SPDX()
+	}
+	return spdxLics
+}
+
+// describe renders a file's licenses for the report, and determines whether
+// the file is ignored or fails the documentation check.
+func describe(lics []License) (licStr string, ignore, undoc bool) {
+	lics = reported(lics)
+	licStr = fmt.Sprint(lics[0])
+	for i, lic := range lics {
+		if i > 0 {
+			licStr = licStr + `, ` + fmt.Sprint(lic)
+		}
+		if strings.HasSuffix(string(lic), `!`) {
+			undoc = true
+		}
+	}
+	return licStr, Has(lics, License(`Ignore`)), undoc
+}
+
+// scan identifies the licenses of every file beneath the roots, applying
+// overrides, LICENSE file inheritance and the documentation check.
+func scan(roots ...string) (map[string][]License, error) {
+	scanSpan = startSpan(`scan`, nil)
+	scanSpan.set(`weasel.roots`, strings.Join(roots, `, `))
+	defer scanSpan.finish()
+
+	spdxTags.Lock()
+	spdxTags.byName = make(map[string][]License)
+	spdxTags.Unlock()
+	forgetAbandoned()
+	wk := &walker{files: make(map[string][]License)}
+	if failFast && baselineFile != `` {
+		/* A baseline which cannot be read accepts nothing here. */
+		wk.baseline, _ = readBaseline(baselineFile)
+	}
+	var err error
+	walkSpan := startSpan(`walk`, scanSpan)
+	for _, root := range roots {
+		if err = wk.walk(root); err != nil {
+			break
+		}
+	}
+	if err != nil && err != errInterrupted {
+		/* The files not yet begun go with the walk, rather than being waited for. */
+		atomic.StoreInt32(&wk.abandoned, 1)
+	}
+	wk.wg.Wait()
+	files := wk.files
+	if err == errInterrupted {
+		/* The files begun are reported, marked as a partial report. */
+		err = nil
+	}
+	walkSpan.set(`weasel.files`, strconv.Itoa(len(files)))
+	walkSpan.finish()
+	if err == nil && failingFast() {
+		/* The walk was done before the failure, but not the files it found. */
+		err = errFailedFast
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inheritSpan := startSpan(`inherit`, scanSpan)
+
+	/* LICENSE files outside the roots may still be inherited from. */
+	outside := make(map[string][]License)
+	inherited := func(licPath string) []License {
+		if lics, ok := files[licPath]; ok {
+			return lics
+		}
+		if lics, ok := outside[licPath]; ok {
+			return lics
+		}
+		var lics []License
+		if info, err := statFile(licPath); err == nil && !info.IsDir() {
+			if found, err := fileLicenses(licPath); err == nil {
+				lics = canonicalAll(licPath, Collide(Uniq(append(append([]License(nil), override[licPath]...), found...))))
+			}
+		}
+		outside[licPath] = lics
+		return lics
+	}
+
+	/* The licenses of the LICENSE or dataset metadata nearest a file, below the root, and its path. */
+	nearestFrom := func(name string) ([]License, string) {
+		parts := strings.Split(filepath.ToSlash(name), `/`)
+		for i := len(parts) - 1; i > 0; i-- {
+			dir := strings.Join(parts[:i], `/`)
+			for _, licName := range []string{`LICENSE`, `LICENCE`, `LICENSE.md`, `LICENCE.md`, `LICENSE.txt`, `LICENCE.txt`} {
+				licPath := filepath.FromSlash(dir + `/` + licName)
+				if lics := inherited(licPath); len(lics) != 0 {
+					return lics, licPath
+				}
+			}
+			if !dataDir(dir) {
+				continue
+			}
+			for _, metaName := range datasetFiles {
+				metaPath := filepath.FromSlash(dir + `/` + metaName)
+				if metaPath == name {
+					continue
+				}
+				if lics := dataLicenses(inherited(metaPath)); len(lics) != 0 {
+					return lics, metaPath
+				}
+			}
+		}
+		return nil, ``
+	}
+	nearest := func(name string) []License {
+		lics, _ := nearestFrom(name)
+		return lics
 	}
 
 	for name, licenses := range files {
-		if len(licenses) != 0 {
-			if len(licenses) > 1 || (licenses[0] != License(`Apache`) && licenses[0] != License(`Docs`) && licenses[0] != License(`Empty`) && licenses[0] != License(`Ignore`)) {
-				if !documented.Documents(name) {
-					for i, lic := range licenses {
-						if lic != License(`Apache`) && lic != License(`Docs`) && lic != License(`Empty`) && lic != License(`Ignore`) {
-							licenses[i] = License(string(licenses[i]) + `!`)
-						}
-					}
+		if len(licenses) == 0 {
+			lics, from := nearestFrom(name)
+			for _, license := range lics {
+				if license != License(`Docs`) {
+					files[name] = append(files[name], License(string(license)+"~"))
 				}
 			}
+			if len(files[name]) != 0 {
+				recordInheritance(name, from)
+			}
 		}
 	}
 
+	inheritSpan.finish()
+
+	conflictSpan := startSpan(`markSPDXConflicts`, scanSpan)
+	markSPDXConflicts(files, nearest)
+	conflictSpan.finish()
+
+	markSpan := startSpan(`markUndocumented`, scanSpan)
+	markUndocumented(files)
+	markSpan.finish()
+
+	kindSpan := startSpan(`filekind`, scanSpan)
 	for name, licenses := range files {
 		if len(licenses) == 0 {
 			kind := filekind(name)
@@ -285,83 +1255,313 @@ forUnknownFiles:
 		}
 	}
 
-	var filenames []string
-	for filename := range files {
-		filenames = append(filenames, filename)
+	kindSpan.finish()
+
+	vendoredSpan := startSpan(`markVendored`, scanSpan)
+	markVendored(files)
+	vendoredSpan.finish()
+
+	componentSpan := startSpan(`markMissingComponents`, scanSpan)
+	markMissingComponents(files)
+	componentSpan.finish()
+
+	declaredSpan := startSpan(`markDeclaredMismatch`, scanSpan)
+	markDeclaredMismatch(files)
+	declaredSpan.finish()
+
+	duplicateSpan := startSpan(`markDuplicateLicenses`, scanSpan)
+	markDuplicateLicenses(files)
+	duplicateSpan.finish()
+
+	fixtureSpan := startSpan(`markFixtures`, scanSpan)
+	markFixtures(files)
+	fixtureSpan.finish()
+
+	return files, nil
+}
+
+// markUndocumented marks with `!` the licenses of every file which needs
+// documenting in LICENSE but isn't.
+func markUndocumented(files map[string][]License) {
+	for name, licenses := range files {
+		if len(licenses) != 0 {
+			expected := expectedLicense(name)
+			_, ruled := ruleFor(name)
+			/* A rule is met by the license a file inherits, too. */
+			if ruled && licenses[0] == License(string(expected)+`~`) {
+				expected = licenses[0]
+			}
+			if len(licenses) > 1 || (licenses[0] != expected && licenses[0] != License(`Docs`) && licenses[0] != License(`Empty`) && licenses[0] != License(`Ignore`)) {
+				/* Where a rule expects a license, no other will do. */
+				pattern := documented.documenting(name)
+				if ruled {
+					pattern = ``
+				}
+				for i, lic := range licenses {
+					/* Markers such as Fixture or Generated are no licenses to document. */
+					if base, _ := lic.split(); lic != expected && countable(base) && !strings.HasSuffix(string(lic), `!`) {
+						if pattern != `` {
+							recordSuppression(name, Suppression{Kind: `documented`, Source: `LICENSE`, Entry: `@` + pattern})
+							break
+						}
+						licenses[i] = License(string(licenses[i]) + `!`)
+					}
+				}
+			}
+		}
 	}
-	sort.Strings(filenames)
+}
 
-	failed := false
-	for _, filename := range filenames {
-		lics := files[filename]
-		ignore := false
-		undoc := false
-		var licStr string
-		if len(lics) == 0 {
-			licStr = "Unknown!"
-			undoc = true
-		} else {
-			licStr = fmt.Sprint(lics[0])
-			ignore = (licStr == `Ignore`)
-			if len(licStr) > 0 && licStr[len(licStr)-1] == '!' {
-				undoc = true
-			}
-			for _, lic := range lics[1:] {
-				if string(lic) == `Ignore` {
-					ignore = true
+// markVendored tags vendored files, relaxing the documentation check for
+// them under the `report` policy.
+func markVendored(files map[string][]License) {
+	for name, licenses := range files {
+		if Vendored(name) {
+			if vendorPolicy == `document` && len(licenses) == 0 {
+				licenses = []License{License(`Unknown!`)}
+			}
+			if vendorPolicy == `report` {
+				for i, lic := range licenses {
+					base, suffix := lic.split()
+					licenses[i] = License(string(base) + strings.Replace(suffix, `!`, ``, -1))
 				}
-				licStr = licStr + `, ` + fmt.Sprint(lic)
 			}
+			files[name] = append(licenses, License(`Vendored`))
 		}
-		if !ignore {
-			errStr := ""
-			if undoc {
-				errStr = "Error"
-				failed = true
+	}
+}
+
+// walker identifies the licenses of the files beneath the roots of a scan
+// in the background, each in a goroutine of its own, recording them in
+// files.
+type walker struct {
+	files     map[string][]License
+	lock      sync.Mutex
+	wg        sync.WaitGroup
+	abandoned int32     /* Set once the walk fails, so files not yet begun are left. */
+	baseline  *baseline /* Read for --fail-fast, which judges files as they are identified. */
+}
+
+// walk walks the tree beneath root, returning what stopped the walk, if
+// anything did. Files and directories which cannot be read are findings,
+// and collected by recordUnreadable, rather than stopping it.
+func (wk *walker) walk(root string) error {
+	return walkTree(root, func(name string, info os.FileInfo, err error) error {
+		if failingFast() {
+			return errFailedFast
+		}
+		if interrupted() {
+			return errInterrupted
+		}
+		if err != nil {
+			recordUnreadable(name, err)
+			wk.lock.Lock()
+			defer wk.lock.Unlock()
+			wk.files[name] = []License{readError(err)}
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
 			}
-			if undoc || !quiet {
-				fmt.Fprintf(w, "%-6s%40s %s\n", errStr, licStr, filename)
+			return nil
+		}
+
+		if filepath.Base(name) == `.git` {
+			return filepath.SkipDir
+		}
+
+		if source, pattern := gitIgnoredBy(name); source != `` {
+			recordIgnored(name, Suppression{Kind: `gitignore`, Source: source, Entry: pattern})
+			return nil
+		}
+
+		if cfg := configFor(name); cfg != nil && cfg.Ignores(name) {
+			recordIgnored(name, Suppression{Kind: `ignore`, Source: filepath.ToSlash(cfg.File), Entry: cfg.ignoring(name)})
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
+			return nil
 		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		if (info.Mode() & os.ModeSymlink) != 0 {
+			return nil
+		}
+
+		/* FIFOs, devices and sockets may block a read forever, and hold no source. */
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		/* The LICENSE files of other shards are still read to be inherited from. */
+		if !inShard(name) {
+			return nil
+		}
+
+		if info.Size() == 0 {
+			wk.lock.Lock()
+			defer wk.lock.Unlock()
+			wk.files[name] = append(wk.files[name], License("Empty"))
+			return nil
+		}
+
+		wk.wg.Add(1)
+		go wk.identify(name, scanSpan)
+		return nil
+	})
+}
+
+// identify identifies the licenses of a file found by the walk.
+func (wk *walker) identify(name string, parent *span) {
+	defer wk.wg.Done()
+	if failingFast() || interrupted() || atomic.LoadInt32(&wk.abandoned) != 0 {
+		return
 	}
-	for _, extra := range documented.Extra() {
-		fmt.Fprintf(w, "%-6s%40s %s\n", "Error", "Extra-License!", extra)
-		failed = true
+	fileSpan := startSpan(`identify`, parent)
+	fileSpan.set(`weasel.path`, name)
+	defer fileSpan.finish()
+	licenses, err := fileLicenses(name)
+	if err != nil {
+		recordUnreadable(name, err)
+		licenses = []License{readError(err)}
+	} else {
+		if missingHeader(name, licenses) {
+			licenses = append(licenses, License(`Missing-Header!`))
+		}
+		if missingCorporateHeader(name, licenses) {
+			licenses = append(licenses, License(`Missing-Corporate-Header!`))
+		}
+		if unlistedCopyright(name, licenses) {
+			licenses = append(licenses, License(`Unlisted-Copyright!`))
+		}
 	}
 
-	if profile {
-		pprof.StopCPUProfile()
+	wk.lock.Lock()
+	defer wk.lock.Unlock()
+	files := wk.files
+	files[name] = append(files[name], override[name]...)
+	files[name] = append(files[name], licenses...)
+	files[name] = canonicalAll(name, Collide(Uniq(files[name])))
+	if failFast {
+		checkFast(name, files[name], wk.baseline)
 	}
-	if failed {
-		os.Exit(1)
+	if fileSpan != nil {
+		fileSpan.set(`weasel.licenses`, fmt.Sprint(files[name]))
 	}
-	os.Exit(0)
 }
 
+// readError describes a failure to read a file as a finding, such as
+// `Error: permission denied!`.
+func readError(err error) License {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	return License("Error: " + err.Error() + "!")
+}
+
+// isReadError reports whether the rendered licenses describe a read error.
+func isReadError(licStr string) bool {
+	return strings.HasPrefix(licStr, `Error: `)
+}
+
+// fileTimeout bounds how long identifying a single file may take. Zero
+// means no limit.
+var fileTimeout time.Duration
+
 func fileLicenses(name string) ([]License, error) {
-	f, err := os.Open(name)
+	if fileTimeout <= 0 {
+		return identifyFile(name)
+	}
+
+	type result struct {
+		licenses []License
+		err      error
+	}
+	ch := make(chan result, 1)
+	slot, stopAwaiting := awaitSlot(name)
+	go func() {
+		licenses, err := identifyFile(name)
+		ch <- result{licenses, err}
+	}()
+
+	/* Waiting for a slot of --max-open-files doesn't count against the time. */
+	select {
+	case r := <-ch:
+		stopAwaiting()
+		return r.licenses, r.err
+	case <-slot:
+	}
+
+	timer := time.NewTimer(fileTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.licenses, r.err
+	case <-timer.C:
+		/* The identification is abandoned, and its goroutine left to finish or block. */
+		abandonFiles(name)
+		return []License{License(`Timeout!`)}, nil
+	}
+}
+
+func identifyFile(name string) ([]License, error) {
+	if resumed, ev, ok := resumedIdentification(name); ok {
+		recordEvidence(name, ev)
+		recordSPDXTags(name, ev)
+		return resumed, nil
+	}
+	f, err := openThrottled(name, name)
 	if err != nil {
 		return nil, err
 	}
+	licenses, evidence, err := identifyContent(name, f)
+	if err == nil {
+		recordEvidence(name, evidence)
+		recordSPDXTags(name, evidence)
+		checkpointIdentified(name, licenses, evidence)
+	}
+	return licenses, err
+}
 
-	return identifyLicenses(f)
+// identifyContent identifies the licenses of the named file's contents,
+// which it closes, and what in them led to each identification. The
+// contents may be those of the worktree or of a commit.
+func identifyContent(name string, in io.ReadSeekCloser) ([]License, []Evidence, error) {
+	f, err := sourceText(name, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	if licenseLike(name) {
+		return identifyLicenseLike(f)
+	}
+	return identifyEvidence(f)
 }
 
 func identifyLicenses(in io.Reader) ([]License, error) {
+	licenses, _, err := identifyEvidence(in)
+	return licenses, err
+}
 
-	ch := make(chan string, 32)
+// identifyEvidence identifies the licenses of in, and what in it led to
+// each identification.
+func identifyEvidence(in io.Reader) ([]License, []Evidence, error) {
+
+	ch := make(chan token, 32)
+	var err error
 	go func() {
-		s := bufio.NewScanner(in)
-		s.Split(bufio.ScanWords)
-		for s.Scan() {
-			s := strings.ToLower(stripPunc(s.Text()))
-			if len(s) > 0 {
-				ch <- s
-			}
-		}
+		err = readWords(in, func(word string, lineNum int) bool {
+			ch <- token{word, lineNum}
+			return true
+		})
 		close(ch)
 	}()
 
-	licenses := newMultiMatcher(ch)
-	return licenses, nil
+	/* The matcher reads until ch is closed, so err is set by its return. */
+	licenses, evidence := newMultiMatcher(ch)
+	if err != nil {
+		return nil, nil, err
+	}
+	return licenses, evidence, nil
 }
@@ -19,10 +19,7 @@ under the License.
 package main
 
 import (
-	"bytes"
-	"runtime"
 	"sort"
-	"strconv"
 	"strings"
 	"unicode"
 )
@@ -102,120 +99,188 @@ func Collide(lics []License) []License {
 	return newLics
 }
 
-func getGID() uint64 {
-	b := make([]byte, 64)
-	b = b[:runtime.Stack(b, false)]
-	b = bytes.TrimPrefix(b, []byte("goroutine "))
-	b = b[:bytes.IndexByte(b, ' ')]
-	n, _ := strconv.ParseUint(string(b), 10, 64)
-	return n
+// licensePattern is a phrase which identifies a license.
+type licensePattern struct {
+	words   []string
+	license License
 }
 
-func wordMatcher(words []string) func(out *bool) (in chan<- string, done <-chan struct{}) {
-	return func(out *bool) (in chan<- string, done <-chan struct{}) {
-		ch := make(chan string, 32)
-		doneCh := make(chan struct{})
-		go func() {
-			defer func() { close(doneCh) }()
-			defer func() {
-				for _ = range ch {
-				}
-			}()
-			if len(words) == 0 {
-				*out = true
-				return
-			}
+// licensePatterns are the hand-written phrases which identify each license.
+var licensePatterns = []licensePattern{
+	{wordsApache, License("Apache")},
+	{wordsApache2, License("Apache")},
+	{wordsApache3, License("Apache")},
+	{wordsBSD, License("BSD")},
+	{wordsBSD2, License("BSD")},
+	{wordsMIT, License("MIT")},
+	{wordsMIT2, License("MIT")},
+	{wordsGoBSD, License("GoBSD")},
+	{wordsISC, License("ISC")},
+	{wordsGen, License("Generated")},
+	{wordsX11, License("X11")},
+	{wordsWTFPL, License("WTFPL")},
+	{wordsOFL, License("OFL")},
+	{wordsGPL, License("GPL/LGPL")},
+	{wordsGPL2, License("GPL/LGPL")},
+	{wordsGPL3, License("GPL/LGPL")},
+	{wordsGPL4, License("GPL/LGPL")},
+	{wordsLGPL, License("GPL/LGPL")},
+	{wordsLGPL2, License("GPL/LGPL")},
+	{wordsLGPL3, License("GPL/LGPL")},
+	{wordsLGPL4, License("GPL/LGPL")},
+}
 
-			i := 0
-			for word := range ch {
-				//				fmt.Println(getGID(), "Comparing "+words[i]+" to "+word)
-				if words[i] == word {
-					i++
-				} else {
-					i = 0
-				}
+// acNode is a state of an Aho-Corasick automaton over words: the words
+// matched so far are a prefix of some pattern.
+type acNode struct {
+	next map[string]int
+	fail int   /* The state for the longest proper suffix that is a prefix. */
+	out  []int /* Patterns ending here, including those of fail states. */
+}
 
-				if i == len(words) {
-					*out = true
-					return
-				}
+// automaton matches every pattern in a single pass over the words of a
+// file, however many patterns there are.
+type automaton []acNode
+
+func newAutomaton(patterns [][]string) automaton {
+	ac := automaton{{next: make(map[string]int)}}
+	for p, words := range patterns {
+		state := 0
+		for _, word := range words {
+			next, ok := ac[state].next[word]
+			if !ok {
+				next = len(ac)
+				ac = append(ac, acNode{next: make(map[string]int)})
+				ac[state].next[word] = next
 			}
-		}()
-		return ch, doneCh
+			state = next
+		}
+		ac[state].out = append(ac[state].out, p)
+	}
+
+	/* Breadth first, so that fail states are complete before use. */
+	queue := []int{}
+	for _, child := range ac[0].next {
+		queue = append(queue, child)
 	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for word, child := range ac[state].next {
+			fail := ac[state].fail
+			for fail != 0 && !ac.has(fail, word) {
+				fail = ac[fail].fail
+			}
+			if next, ok := ac[fail].next[word]; ok && next != child {
+				fail = next
+			}
+			ac[child].fail = fail
+			ac[child].out = append(ac[child].out, ac[fail].out...)
+			queue = append(queue, child)
+		}
+	}
+	return ac
 }
 
-type multiMatcher []struct {
-	ch      chan<- string
-	done    <-chan struct{}
-	value   *bool
-	license License
+func (ac automaton) has(state int, word string) bool {
+	_, ok := ac[state].next[word]
+	return ok
 }
 
-func newMultiMatcher(in <-chan string) []License {
-	var mm multiMatcher
-	mmAppend := func(words []string, license License) {
-		value := new(bool)
-		ch, done := wordMatcher(words)(value)
-		mm = append(mm, struct {
-			ch      chan<- string
-			done    <-chan struct{}
-			value   *bool
-			license License
-		}{
-			ch:      ch,
-			done:    done,
-			value:   value,
-			license: license,
-		})
+// step advances from state on word.
+func (ac automaton) step(state int, word string) int {
+	for {
+		if next, ok := ac[state].next[word]; ok {
+			return next
+		}
+		if state == 0 {
+			return 0
+		}
+		state = ac[state].fail
 	}
+}
+
+// builtinPatterns are the phrases weasel knows without configuration.
+var builtinPatterns = append(append(append(licensePatterns, spdxTagPatterns()...), urlPatterns()...), dataLicensePatterns()...)
+
+// matcherPatterns are all the phrases licenseAutomaton matches, including
+// those of the project's custom licenses.
+var matcherPatterns, licenseAutomaton = matchers(nil)
 
-	mmAppend(wordsApache, License("Apache"))
-	mmAppend(wordsApache2, License("Apache"))
-	mmAppend(wordsApache3, License("Apache"))
-	mmAppend(wordsBSD, License("BSD"))
-	mmAppend(wordsBSD2, License("BSD"))
-	mmAppend(wordsMIT, License("MIT"))
-	mmAppend(wordsMIT2, License("MIT"))
-	mmAppend(wordsGoBSD, License("GoBSD"))
-	mmAppend(wordsISC, License("ISC"))
-	mmAppend(wordsGen, License("Generated"))
-	mmAppend(wordsX11, License("X11"))
-	mmAppend(wordsWTFPL, License("WTFPL"))
-	mmAppend(wordsGPL, License("GPL/LGPL"))
-	mmAppend(wordsGPL2, License("GPL/LGPL"))
-	mmAppend(wordsGPL3, License("GPL/LGPL"))
-	mmAppend(wordsGPL4, License("GPL/LGPL"))
-	mmAppend(wordsLGPL, License("GPL/LGPL"))
-	mmAppend(wordsLGPL2, License("GPL/LGPL"))
-	mmAppend(wordsLGPL3, License("GPL/LGPL"))
-	mmAppend(wordsLGPL4, License("GPL/LGPL"))
+func matchers(custom []licensePattern) ([]licensePattern, automaton) {
+	all := append(append([]licensePattern(nil), builtinPatterns...), custom...)
+	patterns := make([][]string, len(all))
+	for i, p := range all {
+		patterns[i] = p.words
+	}
+	return all, newAutomaton(patterns)
+}
 
-	for word := range in {
-		for _, m := range mm {
-			m.ch <- word
+// token is a normalized word, and the number of the line bearing it.
+type token struct {
+	word    string
+	lineNum int
+}
+
+// maxPatternWords is the length of the longest pattern.
+func maxPatternWords() int {
+	n := 1
+	for _, p := range matcherPatterns {
+		if len(p.words) > n {
+			n = len(p.words)
 		}
 	}
-	for _, m := range mm {
-		close(m.ch)
+	return n
+}
+
+func newMultiMatcher(in <-chan token) ([]License, []Evidence) {
+	matched := make([]bool, len(matcherPatterns))
+	lines := make([][2]int, len(matcherPatterns)) /* Lines of the first match. */
+	/* Empty patterns match anything, even an empty file. */
+	for _, p := range licenseAutomaton[0].out {
+		matched[p] = true
+	}
+	state := 0
+	shingles := newShingler()
+	/* recent holds the lines of the last words, to find where a match began. */
+	recent := make([]int, maxPatternWords())
+	n := 0
+	for tok := range in {
+		recent[n%len(recent)] = tok.lineNum
+		n++
+		state = licenseAutomaton.step(state, tok.word)
+		for _, p := range licenseAutomaton[state].out {
+			if !matched[p] {
+				matched[p] = true
+				start := recent[(n-len(matcherPatterns[p].words))%len(recent)]
+				lines[p] = [2]int{start, tok.lineNum}
+			}
+		}
+		shingles.add(tok.word)
 	}
-	for _, m := range mm {
-		<-m.done
+
+	var evidence []Evidence
+	for p, ok := range matched {
+		if !ok {
+			continue
+		}
+		evidence = append(evidence, Evidence{
+			License:   matcherPatterns[p].license,
+			Phrase:    strings.Join(matcherPatterns[p].words, ` `),
+			StartLine: lines[p][0],
+			EndLine:   lines[p][1],
+		})
 	}
+	fuzzy := shingles.matches()
+	evidence = append(withinTexts(evidence, fuzzy), fuzzy...)
 
 	var licenses []License
-doMatcher:
-	for _, m := range mm {
-		if *m.value {
-			for _, lic := range licenses {
-				if lic == m.license {
-					continue doMatcher
-				}
-			}
-			licenses = append(licenses, m.license)
+	for _, ev := range evidence {
+		if !Has(licenses, ev.License) {
+			licenses = append(licenses, ev.License)
 		}
 	}
-	return licenses
+	return licenses, evidence
 }
 
 func stripPunc(s string) string {
@@ -253,6 +318,7 @@ var (
 	wordsGen     = makeWords(`DO NOT MODIFY THE FIRST PART OF THIS FILE`)
 	wordsX11     = makeWords(`X11`)
 	wordsWTFPL   = makeWords(`WTFPL`)
+	wordsOFL     = makeWords(`SIL Open Font License`)
 	wordsGPL     = makeWords(`GNU General Public License`)
 	wordsGPL2    = makeWords(`GPL`)
 	wordsGPL3    = makeWords(`GPLv2`)
@@ -0,0 +1,356 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/comcast/weasel/headers"
+)
+
+// lspMessage is a JSON-RPC request, response or notification.
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspCodeAction struct {
+	Title       string          `json:"title"`
+	Kind        string          `json:"kind"`
+	Diagnostics []lspDiagnostic `json:"diagnostics,omitempty"`
+	Edit        struct {
+		Changes map[string][]lspTextEdit `json:"changes"`
+	} `json:"edit"`
+}
+
+type lspDocument struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+}
+
+// lspServer checks the files an editor opens and saves, as
+// `weasel check` would.
+type lspServer struct {
+	w           io.Writer
+	diagnostics map[string][]lspDiagnostic /* The last published, by URI. */
+	fixable     map[string]bool            /* Whether a header may be inserted, by URI. */
+}
+
+// serveLSP speaks the Language Server Protocol over in and out until the
+// client exits. It returns the exit status.
+func serveLSP(in io.Reader, out io.Writer) int {
+	explain = true
+	loadLSPProject()
+	s := &lspServer{w: out, diagnostics: make(map[string][]lspDiagnostic), fixable: make(map[string]bool)}
+	r := bufio.NewReader(in)
+	shutdown := false
+	for {
+		b, err := readLSPMessage(r)
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintln(os.Stderr, "Cannot read LSP message: "+err.Error())
+			}
+			return 1
+		}
+		var msg lspMessage
+		if err := json.Unmarshal(b, &msg); err != nil {
+			s.reply(nil, nil, &lspError{-32700, err.Error()})
+			continue
+		}
+
+		switch msg.Method {
+		case `initialize`:
+			s.reply(msg.ID, map[string]interface{}{
+				`capabilities`: map[string]interface{}{
+					`textDocumentSync`:   map[string]interface{}{`openClose`: true, `change`: 0, `save`: true},
+					`codeActionProvider`: true,
+				},
+				`serverInfo`: map[string]string{`name`: `weasel`},
+			}, nil)
+		case `textDocument/didOpen`, `textDocument/didSave`:
+			var doc lspDocument
+			if json.Unmarshal(msg.Params, &doc) == nil {
+				s.check(doc.TextDocument.URI)
+			}
+		case `textDocument/didClose`:
+			var doc lspDocument
+			if json.Unmarshal(msg.Params, &doc) == nil {
+				delete(s.diagnostics, doc.TextDocument.URI)
+				delete(s.fixable, doc.TextDocument.URI)
+				s.publish(doc.TextDocument.URI, nil)
+			}
+		case `textDocument/codeAction`:
+			var doc lspDocument
+			json.Unmarshal(msg.Params, &doc)
+			s.reply(msg.ID, s.codeActions(doc.TextDocument.URI), nil)
+		case `shutdown`:
+			shutdown = true
+			s.reply(msg.ID, nil, nil)
+		case `exit`:
+			if shutdown {
+				return 0
+			}
+			return 1
+		default:
+			/* Notifications the server doesn't handle are dropped, requests refused. */
+			if msg.ID != nil {
+				s.reply(msg.ID, nil, &lspError{-32601, "method not found: " + msg.Method})
+			}
+		}
+	}
+}
+
+// loadLSPProject reads the overrides and LICENSE documentation afresh.
+func loadLSPProject() {
+	override = make(map[string][]License)
+	loadOverrides()
+	documented = nil
+	if _, err := statFile(`LICENSE`); err == nil {
+		recordDocumentedLicenses()
+	}
+}
+
+// readLSPMessage reads the content of a message after its headers.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == `` {
+			break
+		}
+		parts := strings.SplitN(line, `:`, 2)
+		if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), `Content-Length`) {
+			if length, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+				return nil, errors.New("malformed Content-Length: " + parts[1])
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("no Content-Length")
+	}
+	b := make([]byte, length)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+func (s *lspServer) send(msg lspMessage) {
+	msg.JSONRPC = `2.0`
+	b, err := json.Marshal(msg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot write LSP message: "+err.Error())
+		return
+	}
+	fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+}
+
+// reply answers a request. A null result is sent as such, as the protocol
+// requires one of result or error.
+func (s *lspServer) reply(id *json.RawMessage, result interface{}, rpcErr *lspError) {
+	if id == nil {
+		null := json.RawMessage(`null`)
+		id = &null
+	}
+	if result == nil && rpcErr == nil {
+		result = json.RawMessage(`null`)
+	}
+	s.send(lspMessage{ID: id, Result: result, Error: rpcErr})
+}
+
+func (s *lspServer) publish(uri string, diagnostics []lspDiagnostic) {
+	if diagnostics == nil {
+		diagnostics = []lspDiagnostic{}
+	}
+	params, _ := json.Marshal(map[string]interface{}{`uri`: uri, `diagnostics`: diagnostics})
+	s.send(lspMessage{Method: `textDocument/publishDiagnostics`, Params: params})
+}
+
+// uriPath returns the path of a file: URI relative to the project root,
+// or false for documents outside it.
+func uriPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != `file` {
+		return ``, false
+	}
+	cur, err := os.Getwd()
+	if err != nil {
+		return ``, false
+	}
+	name := filepath.FromSlash(u.Path)
+	if len(name) > 2 && name[0] == '\\' && name[2] == ':' {
+		/* file:///C:/... on Windows. */
+		name = name[1:]
+	}
+	rel, err := filepath.Rel(cur, name)
+	if err != nil || rel == `..` || strings.HasPrefix(rel, `..`+string(filepath.Separator)) {
+		return ``, false
+	}
+	return rel, true
+}
+
+// check scans a document and publishes a diagnostic for each finding.
+func (s *lspServer) check(uri string) {
+	name, ok := uriPath(uri)
+	if !ok {
+		return
+	}
+	switch filepath.Base(name) {
+	case `LICENSE`, `.dependency_license`, configName:
+		loadLSPProject()
+		configs.Lock()
+		configs.byDir = make(map[string]*Config)
+		configs.Unlock()
+	}
+	files, err := scan(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot check "+name+": "+err.Error())
+		return
+	}
+
+	var diagnostics []lspDiagnostic
+	fixable := false
+	_, ignore, undoc := describe(files[name])
+	if !ignore && undoc {
+		lics := files[name]
+		if len(lics) == 0 {
+			lics = []License{License(`Unknown!`)}
+		}
+		for _, lic := range lics {
+			if !strings.HasSuffix(string(lic), `!`) {
+				continue
+			}
+			base, _ := lic.split()
+			d := lspDiagnostic{Severity: 1, Code: codeOf(lic), Source: `weasel`, Message: licenseFinding(name, lic)}
+			for _, ev := range evidenceFor(name) {
+				if ev.License+`!` == lic && ev.StartLine > 0 {
+					d.Range = lspRange{lspPosition{ev.StartLine - 1, 0}, lspPosition{ev.EndLine, 0}}
+					break
+				}
+			}
+			if base == `Unknown` || base == `Missing-Header` {
+				fixable = true
+			}
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	s.diagnostics[uri] = diagnostics
+	s.fixable[uri] = fixable
+	s.publish(uri, diagnostics)
+}
+
+// codeActions offers to insert the configured header into a document
+// lacking it.
+func (s *lspServer) codeActions(uri string) []lspCodeAction {
+	actions := []lspCodeAction{}
+	name, ok := uriPath(uri)
+	if !ok || !s.fixable[uri] {
+		return actions
+	}
+	style, ok := headers.StyleFor(name)
+	if !ok {
+		return actions
+	}
+	text, err := fixText(name)
+	if err != nil {
+		return actions
+	}
+
+	line := 0
+	if b, err := readFile(name); err == nil && (strings.HasPrefix(string(b), `#!`) || strings.HasPrefix(string(b), `<?xml`)) {
+		line = 1
+	}
+	at := lspRange{lspPosition{line, 0}, lspPosition{line, 0}}
+	action := lspCodeAction{Title: `Insert license header`, Kind: `quickfix`, Diagnostics: s.diagnostics[uri]}
+	action.Edit.Changes = map[string][]lspTextEdit{uri: {{at, headers.Render(text, style)}}}
+	return append(actions, action)
+}
+
+// licenseFinding describes what is wrong with a file, for a license it is
+// reported with that ends in `!`.
+func licenseFinding(name string, lic License) string {
+	base, _ := lic.split()
+	switch {
+	case strings.HasPrefix(string(base), `Unknown`):
+		return "missing " + string(canonical(name, expectedLicense(name))) + " license header"
+	case base == `Missing-Header`:
+		return "missing the header its " + configName + " requires"
+	case base == `Missing-Corporate-Header`:
+		return "missing the corporate header its " + configName + " requires"
+	case base == `Missing-License-Notice`:
+		return "missing the license notice its " + configName + " requires"
+	case base == `Missing-Copyright`:
+		return "missing the copyright line its " + configName + " requires"
+	case base == `Missing-SPDX-Tag`:
+		return "missing the SPDX-License-Identifier tag its " + configName + " requires"
+	case base == `Unlisted-Copyright`:
+		return "credits a copyright holder its " + configName + " does not list"
+	case base == `SPDX-Conflict`:
+		return "SPDX-License-Identifier tag names a license other than its LICENSE file's"
+	case base == `Declared-Mismatch`:
+		return "declares a license other than most of the files' headers carry"
+	case base == `Timeout`:
+		return "took too long to identify"
+	case isReadError(string(lic)):
+		return "cannot be read: " + strings.TrimPrefix(string(base), `Error: `)
+	}
+	return string(base) + " license is not documented for this file"
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var mergeOutput string
+
+// loadReport adds the results of a JSON or NDJSON report to files. The
+// documentation markers of licenses and the vendoring and fixture markers
+// are removed, so that those checks can be made afresh over the combined
+// results; the other findings stand as reported. Licenses a report names
+// by SPDX identifier, as with --spdx-ids, are named as the matchers name
+// them.
+func loadReport(name string, files map[string][]License) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	for {
+		var v struct {
+			SchemaVersion string       `json:"schemaVersion"`
+			Type          string       `json:"type"`
+			Path          string       `json:"path"`
+			Licenses      []License    `json:"licenses"`
+			Files         []FileResult `json:"files"`
+		}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.New(name + ": " + err.Error())
+		}
+		if v.SchemaVersion != schemaVersion {
+			return errors.New(name + ": unsupported schemaVersion `" + v.SchemaVersion + "`")
+		}
+
+		results := v.Files
+		if v.Type == `file` {
+			results = []FileResult{{Path: v.Path, Licenses: v.Licenses}}
+		}
+		for _, res := range results {
+			path := filepath.FromSlash(res.Path)
+			if _, ok := files[path]; !ok {
+				files[path] = nil
+			}
+			for _, lic := range res.Licenses {
+				/* Markers the merge derives afresh are dropped. */
+				if lic == `Vendored` || lic == `Fixture` || lic == `Unknown!` {
+					continue
+				}
+				if isReadError(string(lic)) {
+					recordUnreadable(path, errors.New(strings.TrimSuffix(strings.TrimPrefix(string(lic), `Error: `), `!`)))
+				}
+				base, suffix := lic.split()
+				/* Findings such as Missing-Header! are kept, as nothing checks them afresh. */
+				if !countable(base) {
+					files[path] = append(files[path], lic)
+					continue
+				}
+				/* A report written with --spdx-ids names licenses by SPDX identifier. */
+				files[path] = append(files[path], License(string(spdxName(string(base)))+strings.Replace(suffix, `!`, ``, -1)))
+			}
+		}
+	}
+}
+
+// merge combines the results of several reports, such as those of sharded
+// runs, and checks documentation over the whole as a single run would.
+func merge(names []string) (map[string][]License, []string, error) {
+	files := make(map[string][]License)
+	for _, name := range names {
+		if err := loadReport(name, files); err != nil {
+			return nil, nil, err
+		}
+	}
+	for name, lics := range files {
+		files[name] = Uniq(lics)
+	}
+
+	markUndocumented(files)
+	markVendored(files)
+	markFixtures(files)
+
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	return files, documented.ExtraAmong(paths), nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// mergeTree has a file of each kind of finding a merge must carry over or
+// judge afresh: documented and undocumented licenses, a fixture, a
+// vendored package and a file bearing none.
+var mergeTree = map[string]string{
+	`LICENSE`:                       "Apache License\nVersion 2.0, January 2004\n\n@mit.go\n",
+	`.weasel.yml`:                   "fixtures: [testdata]\n",
+	`main.go`:                       "// Licensed under the Apache License, Version 2.0\npackage main\n",
+	`mit.go`:                        "// SPDX-License-Identifier: MIT\npackage main\n",
+	`gpl.go`:                        "// SPDX-License-Identifier: GPL-2.0-only\npackage main\n",
+	`testdata/bsd.go`:               "// SPDX-License-Identifier: BSD-2-Clause\npackage testdata\n",
+	`vendor/example.com/lib/lib.go`: "// SPDX-License-Identifier: MIT\npackage lib\n",
+	`notes.go`:                      "package main\n",
+}
+
+// writeShards writes the results of files as reports of the given number
+// of shards, returning their names.
+func writeShards(t *testing.T, files map[string][]License, shards int) []string {
+	t.Helper()
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([][]FileResult, shards)
+	for i, name := range names {
+		res := FileResult{Path: filepath.ToSlash(name), Licenses: reported(files[name])}
+		results[i%shards] = append(results[i%shards], res)
+	}
+	dir := t.TempDir()
+	var reports []string
+	for i := range results {
+		report := filepath.Join(dir, `shard`+strconv.Itoa(i)+`.json`)
+		f, err := os.Create(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writeJSON(f, newReport(results[i], nil, ``, false))
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func TestMergeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		spdx   bool
+		shards int
+	}{
+		{`one report`, false, 1},
+		{`sharded`, false, 3},
+		{`one report with SPDX identifiers`, true, 1},
+		{`sharded with SPDX identifiers`, true, 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inTree(t, mergeTree)
+			useSPDX = tc.spdx
+			defer func() { useSPDX = false }()
+
+			override = make(map[string][]License)
+			loadOverrides()
+			documented = nil
+			recordDocumentedLicenses()
+			scanned, err := scan(`.`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reports := writeShards(t, scanned, tc.shards)
+
+			merged, _, err := merge(reports)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(merged) != len(scanned) {
+				t.Errorf("merged %d files, scanned %d", len(merged), len(scanned))
+			}
+			for name, lics := range scanned {
+				want, _, wantUndoc := describe(lics)
+				got, _, gotUndoc := describe(merged[name])
+				if got != want || gotUndoc != wantUndoc {
+					t.Errorf("%s: merged %q (undocumented %v), scanned %q (undocumented %v)", name, got, gotUndoc, want, wantUndoc)
+				}
+			}
+			if !Has(merged[filepath.FromSlash(`testdata/bsd.go`)], License(`Fixture`)) {
+				t.Errorf("testdata/bsd.go is no fixture: %v", merged[filepath.FromSlash(`testdata/bsd.go`)])
+			}
+			if _, _, undoc := describe(merged[`gpl.go`]); !undoc {
+				t.Errorf("gpl.go is documented: %v", merged[`gpl.go`])
+			}
+		})
+	}
+}
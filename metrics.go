@@ -0,0 +1,120 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// metricsFile is where to write the metrics of a run in the Prometheus
+// text exposition format, for the node_exporter textfile collector. weasel
+// has no server mode, so there is no /metrics endpoint to scrape.
+var metricsFile string
+
+/* cacheHits counts license-like files whose text was already identified. */
+var cacheHits int64
+
+func countCacheHit() {
+	atomic.AddInt64(&cacheHits, 1)
+}
+
+// writeMetrics writes the metrics of a scan of files taking duration.
+func writeMetrics(w io.Writer, files map[string][]License, duration time.Duration, failed bool) {
+	detections := make(map[License]int)
+	scanned := 0
+	for _, lics := range files {
+		/* Ignored files are left out, as they are of the report. */
+		if _, ignore, _ := describe(lics); ignore {
+			continue
+		}
+		scanned++
+		for _, lic := range reported(lics) {
+			base, _ := lic.split()
+			detections[base]++
+		}
+	}
+	var names []string
+	for lic := range detections {
+		names = append(names, string(lic))
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "# HELP weasel_files_scanned_total Files scanned.")
+	fmt.Fprintln(w, "# TYPE weasel_files_scanned_total counter")
+	fmt.Fprintf(w, "weasel_files_scanned_total %d\n", scanned)
+	/* Which of the files alike is read first, and so misses, is down to chance. */
+	if !reproducible {
+		fmt.Fprintln(w, "# HELP weasel_cache_hits_total License-like files whose text was already identified.")
+		fmt.Fprintln(w, "# TYPE weasel_cache_hits_total counter")
+		fmt.Fprintf(w, "weasel_cache_hits_total %d\n", atomic.LoadInt64(&cacheHits))
+	}
+	fmt.Fprintln(w, "# HELP weasel_detections_total Files bearing each license.")
+	fmt.Fprintln(w, "# TYPE weasel_detections_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "weasel_detections_total{license=%s} %d\n", labelValue(name), detections[License(name)])
+	}
+	fmt.Fprintln(w, "# HELP weasel_suppressed_files Files each kind of suppression in each file applied to.")
+	fmt.Fprintln(w, "# TYPE weasel_suppressed_files gauge")
+	for _, c := range suppressionStats() {
+		fmt.Fprintf(w, "weasel_suppressed_files{kind=%s,source=%s} %d\n", labelValue(c.Kind), labelValue(c.Source), c.Files)
+	}
+	if !reproducible {
+		fmt.Fprintln(w, "# HELP weasel_scan_duration_seconds Time taken by the scan.")
+		fmt.Fprintln(w, "# TYPE weasel_scan_duration_seconds gauge")
+		fmt.Fprintf(w, "weasel_scan_duration_seconds %g\n", duration.Seconds())
+	}
+	fmt.Fprintln(w, "# HELP weasel_failed Whether the scan failed its checks.")
+	fmt.Fprintln(w, "# TYPE weasel_failed gauge")
+	failedValue := 0
+	if failed {
+		failedValue = 1
+	}
+	fmt.Fprintf(w, "weasel_failed %d\n", failedValue)
+}
+
+// labelValue quotes a label value as the text exposition format does,
+// escaping only backslashes, double quotes and line feeds.
+func labelValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// saveMetrics writes the metrics to metricsFile, by way of a temporary
+// file so the collector never reads half of them.
+func saveMetrics(files map[string][]License, duration time.Duration, failed bool) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(metricsFile), `.weasel-metrics`)
+	if err != nil {
+		return err
+	}
+	writeMetrics(tmp, files, duration, failed)
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), metricsFile)
+}
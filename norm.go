@@ -0,0 +1,125 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// nfc returns s in Unicode Normalization Form C. File names are normalized
+// before they are compared, since macOS decomposes those it stores while
+// LICENSE and .dependency_license are usually written precomposed.
+func nfc(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+
+	var rs []rune
+	for _, r := range s {
+		rs = decompose(rs, r)
+	}
+
+	/* Canonical ordering: sort each run of combining marks by class. */
+	for i := 0; i < len(rs); {
+		if combiningClasses[rs[i]] == 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(rs) && combiningClasses[rs[j]] != 0 {
+			j++
+		}
+		run := rs[i:j]
+		sort.SliceStable(run, func(a, b int) bool {
+			return combiningClasses[run[a]] < combiningClasses[run[b]]
+		})
+		i = j
+	}
+
+	out := make([]rune, 0, len(rs))
+	starter := -1
+	last := -1 /* Class of the last character since the starter, -1 if there is none. */
+	for _, r := range rs {
+		class := int(combiningClasses[r])
+		if starter >= 0 && (last == -1 || last < class) {
+			if composite, ok := compose(out[starter], r); ok {
+				out[starter] = composite
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+			last = -1
+		} else {
+			last = class
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// decompose appends the full canonical decomposition of r to rs.
+func decompose(rs []rune, r rune) []rune {
+	if r >= hangulSBase && r < hangulSBase+hangulSCount {
+		s := r - hangulSBase
+		rs = append(rs, hangulLBase+s/hangulNCount, hangulVBase+(s%hangulNCount)/hangulTCount)
+		if t := s % hangulTCount; t != 0 {
+			rs = append(rs, hangulTBase+t)
+		}
+		return rs
+	}
+	d, ok := canonicalDecomposition[r]
+	if !ok {
+		return append(rs, r)
+	}
+	for _, dr := range d {
+		rs = decompose(rs, dr)
+	}
+	return rs
+}
+
+// compose returns the primary composite of a and b, if there is one.
+func compose(a, b rune) (rune, bool) {
+	if a >= hangulLBase && a < hangulLBase+hangulLCount && b >= hangulVBase && b < hangulVBase+hangulVCount {
+		return hangulSBase + ((a-hangulLBase)*hangulVCount+(b-hangulVBase))*hangulTCount, true
+	}
+	if a >= hangulSBase && a < hangulSBase+hangulSCount && (a-hangulSBase)%hangulTCount == 0 && b > hangulTBase && b < hangulTBase+hangulTCount {
+		return a + (b - hangulTBase), true
+	}
+	r, ok := canonicalComposition[[2]rune{a, b}]
+	return r, ok
+}
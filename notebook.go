@@ -0,0 +1,126 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// notebook is the part of a Jupyter notebook (nbformat 4) bearing text.
+type notebook struct {
+	Cells []struct {
+		CellType string          `json:"cell_type"`
+		Source   json.RawMessage `json:"source"`
+	} `json:"cells"`
+}
+
+// openSource opens a file for identification, decompressing it if need be.
+// Notebooks are JSON, whose quoting and escaped newlines would break up
+// the words of a header, so for them it yields the source of the code and
+// markdown cells instead. For fonts and images, it yields the copyright
+// and license of their metadata, for PDF and office documents, their text,
+// and for source maps and the bundles they map, the bundled sources.
+func openSource(name string) (io.ReadCloser, error) {
+	tf, err := openThrottled(name, name)
+	if err != nil {
+		return nil, err
+	}
+	return sourceText(name, tf)
+}
+
+// sourceText is openSource for contents already opened, such as those of
+// a file in history, read as the name calls for. It closes in.
+func sourceText(name string, in io.ReadSeekCloser) (io.ReadCloser, error) {
+	f, err := decompress(name, in)
+	if err != nil {
+		return nil, err
+	}
+	extract := extractor(name)
+	if extract == nil {
+		return f, nil
+	}
+
+	/* Closed before extracting, which may open the files referred to. */
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	if text, ok := extract(b); ok {
+		b = text
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// extractor returns what reads the text to identify out of a file whose
+// contents aren't text as they stand, or nil for one whose are.
+func extractor(name string) func([]byte) ([]byte, bool) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, `.ipynb`):
+		return notebookSource
+	case isFont(lower):
+		return fontText
+	case isPicture(lower):
+		return pictureText
+	case strings.HasSuffix(lower, `.pdf`):
+		return pdfText
+	case isOffice(lower):
+		return officeText
+	case strings.HasSuffix(lower, `.map`):
+		return sourceMapText
+	case isBundle(lower):
+		return func(b []byte) ([]byte, bool) {
+			return bundleText(name, b)
+		}
+	}
+	return nil
+}
+
+// notebookSource concatenates the cell sources of a notebook. It fails for
+// anything that doesn't parse as one, which is then read as it stands.
+func notebookSource(b []byte) ([]byte, bool) {
+	var nb notebook
+	if err := json.Unmarshal(b, &nb); err != nil || nb.Cells == nil {
+		return nil, false
+	}
+
+	var src bytes.Buffer
+	for _, cell := range nb.Cells {
+		if cell.CellType != `code` && cell.CellType != `markdown` {
+			continue
+		}
+		/* Source is either a string or a list of lines. */
+		var lines []string
+		if err := json.Unmarshal(cell.Source, &lines); err != nil {
+			var text string
+			if err := json.Unmarshal(cell.Source, &text); err != nil {
+				continue
+			}
+			lines = []string{text}
+		}
+		for _, line := range lines {
+			src.WriteString(line)
+		}
+		src.WriteString("\n")
+	}
+	return src.Bytes(), true
+}
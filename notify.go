@@ -0,0 +1,88 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var notifyURL string
+
+// notifyLimit caps how many violations are spelled out in the message text.
+const notifyLimit = 20
+
+// violation is a single error row of the report.
+type violation struct {
+	Path     string   `json:"path"`
+	Licenses string   `json:"licenses"`
+	Codes    []string `json:"codes,omitempty"`
+}
+
+// notification is posted to --notify-url. The `text` field is what Slack and
+// Teams incoming webhooks display; other consumers can use the rest.
+type notification struct {
+	Text       string      `json:"text"`
+	Root       string      `json:"root"`
+	Revision   string      `json:"revision,omitempty"`
+	Violations []violation `json:"violations"`
+}
+
+// notify posts a summary of the violations to a webhook.
+func notify(url string, violations []violation) error {
+	root, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	n := notification{
+		Root:       root,
+		Revision:   revision(),
+		Violations: violations,
+	}
+
+	lines := []string{fmt.Sprintf("weasel found %d license violations in %s", len(violations), root)}
+	for i, v := range violations {
+		if i == notifyLimit {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(violations)-notifyLimit))
+			break
+		}
+		lines = append(lines, withCodes(v.Licenses, v.Codes)+" "+v.Path)
+	}
+	n.Text = strings.Join(lines, "\n")
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, `application/json`, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("webhook responded " + resp.Status)
+	}
+	return nil
+}
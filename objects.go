@@ -0,0 +1,444 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// objectStore is a bucket of S3 or Google Cloud Storage.
+type objectStore interface {
+	list(prefix string) ([]objectInfo, error)
+	get(key string) (io.ReadCloser, error)
+}
+
+type objectInfo struct {
+	key     string
+	size    int64
+	modTime time.Time
+}
+
+var objectClient = http.Client{Timeout: 5 * time.Minute}
+
+// isObjectURL tells whether a target names objects in a bucket, as
+// `s3://bucket/prefix` or `gs://bucket/prefix`.
+func isObjectURL(target string) bool {
+	return strings.HasPrefix(target, `s3://`) || strings.HasPrefix(target, `gs://`)
+}
+
+// objectFS presents the objects beneath a prefix of a bucket as a
+// filesystem, listing them all at once and fetching each object's content
+// only when it is read.
+type objectFS struct {
+	store objectStore
+	files map[string]objectInfo
+	dirs  map[string][]fs.DirEntry
+}
+
+// newObjectFS lists the objects a `s3://` or `gs://` target names. Keys
+// which are not valid paths, such as those holding `//`, are left out.
+func newObjectFS(target string) (*objectFS, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == `` {
+		return nil, errors.New("no bucket in " + target)
+	}
+	prefix := strings.Trim(u.Path, `/`)
+	if prefix != `` {
+		prefix += `/`
+	}
+	var store objectStore = newS3Store(u.Host)
+	if u.Scheme == `gs` {
+		store = newGCSStore(u.Host)
+	}
+	objects, err := store.list(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return newObjectTree(store, objects, prefix), nil
+}
+
+// newObjectTree arranges the objects beneath a prefix into directories by
+// the slashes of their keys.
+func newObjectTree(store objectStore, objects []objectInfo, prefix string) *objectFS {
+	o := &objectFS{store, make(map[string]objectInfo), make(map[string][]fs.DirEntry)}
+	o.dirs[`.`] = nil
+	for _, obj := range objects {
+		name := strings.TrimPrefix(obj.key, prefix)
+		if strings.HasSuffix(name, `/`) || !fs.ValidPath(name) || name == `.` {
+			continue
+		}
+		o.files[name] = obj
+		child := fs.DirEntry(objectStat{path.Base(name), obj.size, obj.modTime, false})
+		for dir := path.Dir(name); ; dir = path.Dir(dir) {
+			_, known := o.dirs[dir]
+			o.dirs[dir] = append(o.dirs[dir], child)
+			if known {
+				break
+			}
+			child = objectStat{path.Base(dir), 0, time.Time{}, true}
+		}
+	}
+	for _, entries := range o.dirs {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	}
+	return o
+}
+
+func (o *objectFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: `open`, Path: name, Err: fs.ErrInvalid}
+	}
+	if obj, ok := o.files[name]; ok {
+		return &objectFile{store: o.store, info: obj}, nil
+	}
+	if _, ok := o.dirs[name]; ok {
+		return objectDir{path.Base(name)}, nil
+	}
+	return nil, &fs.PathError{Op: `open`, Path: name, Err: fs.ErrNotExist}
+}
+
+func (o *objectFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := o.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: `readdir`, Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// objectStat describes an object, or a directory of a bucket's keys, both
+// as a file and as an entry of its directory.
+type objectStat struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (s objectStat) Name() string       { return s.name }
+func (s objectStat) Size() int64        { return s.size }
+func (s objectStat) ModTime() time.Time { return s.modTime }
+func (s objectStat) IsDir() bool        { return s.dir }
+func (s objectStat) Sys() interface{}   { return nil }
+
+func (s objectStat) Mode() fs.FileMode {
+	if s.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (s objectStat) Type() fs.FileMode          { return s.Mode().Type() }
+func (s objectStat) Info() (fs.FileInfo, error) { return s, nil }
+
+// objectFile streams an object, requesting it on the first read.
+type objectFile struct {
+	store objectStore
+	info  objectInfo
+	body  io.ReadCloser
+}
+
+func (f *objectFile) Stat() (fs.FileInfo, error) {
+	return objectStat{path.Base(f.info.key), f.info.size, f.info.modTime, false}, nil
+}
+
+func (f *objectFile) Read(b []byte) (int, error) {
+	if f.body == nil {
+		body, err := f.store.get(f.info.key)
+		if err != nil {
+			return 0, err
+		}
+		f.body = body
+	}
+	return f.body.Read(b)
+}
+
+func (f *objectFile) Close() error {
+	if f.body == nil {
+		return nil
+	}
+	return f.body.Close()
+}
+
+type objectDir struct {
+	name string
+}
+
+func (d objectDir) Stat() (fs.FileInfo, error) {
+	return objectStat{d.name, 0, time.Time{}, true}, nil
+}
+
+func (d objectDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: `read`, Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d objectDir) Close() error {
+	return nil
+}
+
+// fetch makes a request of object storage, failing unless it succeeds.
+func fetch(req *http.Request) (io.ReadCloser, error) {
+	resp, err := objectClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, errors.New(req.URL.Host + " responded " + resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// s3Store reads a bucket of S3, or of a service compatible with it at
+// $AWS_ENDPOINT_URL_S3 or $AWS_ENDPOINT_URL. Requests are signed with the
+// credentials in $AWS_ACCESS_KEY_ID, $AWS_SECRET_ACCESS_KEY and
+// $AWS_SESSION_TOKEN, if they are set, and are anonymous otherwise.
+type s3Store struct {
+	bucket   string
+	endpoint string /* Empty for AWS, whose buckets are addressed by host. */
+	region   string
+}
+
+func newS3Store(bucket string) *s3Store {
+	s := &s3Store{bucket: bucket, region: `us-east-1`}
+	for _, name := range []string{`AWS_ENDPOINT_URL_S3`, `AWS_ENDPOINT_URL`} {
+		if endpoint := os.Getenv(name); endpoint != `` {
+			s.endpoint = strings.TrimSuffix(endpoint, `/`)
+			break
+		}
+	}
+	for _, name := range []string{`AWS_REGION`, `AWS_DEFAULT_REGION`} {
+		if region := os.Getenv(name); region != `` {
+			s.region = region
+			break
+		}
+	}
+	return s
+}
+
+// s3Escape escapes as SigV4 requires: everything but the unreserved
+// characters of RFC 3986, and slashes if they are to be kept.
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~', c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteString(`%` + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+func (s *s3Store) request(key string, query map[string]string) (*http.Request, error) {
+	base := `https://` + s.bucket + `.s3.` + s.region + `.amazonaws.com`
+	if s.endpoint != `` {
+		base = s.endpoint + `/` + s3Escape(s.bucket, false)
+	}
+	var params []string
+	for k, v := range query {
+		params = append(params, s3Escape(k, false)+`=`+s3Escape(v, false))
+	}
+	sort.Strings(params)
+	rawQuery := strings.Join(params, `&`)
+	u := base + `/` + s3Escape(key, true)
+	if rawQuery != `` {
+		u += `?` + rawQuery
+	}
+	req, err := http.NewRequest(`GET`, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, rawQuery, time.Now())
+	return req, nil
+}
+
+// sign adds an AWS Signature Version 4 to a body-less request. The query
+// must already be in canonical order.
+func (s *s3Store) sign(req *http.Request, rawQuery string, now time.Time) {
+	access, secret := os.Getenv(`AWS_ACCESS_KEY_ID`), os.Getenv(`AWS_SECRET_ACCESS_KEY`)
+	if access == `` || secret == `` {
+		return
+	}
+	const emptyHash = `e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855`
+	stamp := now.UTC().Format(`20060102T150405Z`)
+	day := stamp[:8]
+	req.Header.Set(`X-Amz-Date`, stamp)
+	req.Header.Set(`X-Amz-Content-Sha256`, emptyHash)
+	headers := []string{`host:` + req.URL.Host, `x-amz-content-sha256:` + emptyHash, `x-amz-date:` + stamp}
+	signed := `host;x-amz-content-sha256;x-amz-date`
+	if token := os.Getenv(`AWS_SESSION_TOKEN`); token != `` {
+		req.Header.Set(`X-Amz-Security-Token`, token)
+		headers = append(headers, `x-amz-security-token:`+token)
+		signed += `;x-amz-security-token`
+	}
+
+	canonical := strings.Join([]string{`GET`, req.URL.EscapedPath(), rawQuery, strings.Join(headers, "\n") + "\n", signed, emptyHash}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	scope := day + `/` + s.region + `/s3/aws4_request`
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := []byte(`AWS4` + secret)
+	for _, part := range []string{day, s.region, `s3`, `aws4_request`, toSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	req.Header.Set(`Authorization`, `AWS4-HMAC-SHA256 Credential=`+access+`/`+scope+`, SignedHeaders=`+signed+`, Signature=`+hex.EncodeToString(key))
+}
+
+func (s *s3Store) list(prefix string) ([]objectInfo, error) {
+	var objects []objectInfo
+	token := ``
+	for {
+		query := map[string]string{`list-type`: `2`, `prefix`: prefix}
+		if token != `` {
+			query[`continuation-token`] = token
+		}
+		req, err := s.request(``, query)
+		if err != nil {
+			return nil, err
+		}
+		body, err := fetch(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string
+				Size         int64
+				LastModified time.Time
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(body).Decode(&page)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range page.Contents {
+			objects = append(objects, objectInfo{c.Key, c.Size, c.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == `` {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *s3Store) get(key string) (io.ReadCloser, error) {
+	req, err := s.request(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return fetch(req)
+}
+
+// gcsStore reads a bucket of Google Cloud Storage through its JSON API, at
+// $STORAGE_EMULATOR_HOST if set, with the bearer token in
+// $GOOGLE_OAUTH_ACCESS_TOKEN if set and anonymously otherwise.
+type gcsStore struct {
+	bucket string
+	base   string
+}
+
+func newGCSStore(bucket string) *gcsStore {
+	base := `https://storage.googleapis.com`
+	if host := os.Getenv(`STORAGE_EMULATOR_HOST`); host != `` {
+		base = strings.TrimSuffix(host, `/`)
+		if !strings.Contains(base, `://`) {
+			base = `http://` + base
+		}
+	}
+	return &gcsStore{bucket, base}
+}
+
+func (g *gcsStore) request(u string) (*http.Request, error) {
+	req, err := http.NewRequest(`GET`, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv(`GOOGLE_OAUTH_ACCESS_TOKEN`); token != `` {
+		req.Header.Set(`Authorization`, `Bearer `+token)
+	}
+	return req, nil
+}
+
+func (g *gcsStore) list(prefix string) ([]objectInfo, error) {
+	var objects []objectInfo
+	token := ``
+	for {
+		u := g.base + `/storage/v1/b/` + url.PathEscape(g.bucket) + `/o?prefix=` + url.QueryEscape(prefix)
+		if token != `` {
+			u += `&pageToken=` + url.QueryEscape(token)
+		}
+		req, err := g.request(u)
+		if err != nil {
+			return nil, err
+		}
+		body, err := fetch(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name    string    `json:"name"`
+				Size    int64     `json:"size,string"`
+				Updated time.Time `json:"updated"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(body).Decode(&page)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			objects = append(objects, objectInfo{item.Name, item.Size, item.Updated})
+		}
+		if page.NextPageToken == `` {
+			return objects, nil
+		}
+		token = page.NextPageToken
+	}
+}
+
+func (g *gcsStore) get(key string) (io.ReadCloser, error) {
+	req, err := g.request(g.base + `/storage/v1/b/` + url.PathEscape(g.bucket) + `/o/` + url.PathEscape(key) + `?alt=media`)
+	if err != nil {
+		return nil, err
+	}
+	return fetch(req)
+}
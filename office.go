@@ -0,0 +1,93 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"regexp"
+	"strings"
+)
+
+// isOffice reports whether a file is an Office Open XML or OpenDocument
+// document, spreadsheet or presentation.
+func isOffice(lower string) bool {
+	for _, ext := range []string{`.docx`, `.xlsx`, `.pptx`, `.odt`, `.ods`, `.odp`} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// officeParts are the parts of a document bearing its text: the body,
+// headers, footers and notes of a word processing document, the strings
+// and sheets of a spreadsheet, the slides and notes of a presentation, and
+// the properties of each, such as its rights.
+var officeParts = regexp.MustCompile(`^(word/(document|header\d*|footer\d*|footnotes|endnotes)|xl/(sharedStrings|worksheets/sheet\d+)|ppt/(slides/slide\d+|notesSlides/notesSlide\d+)|docProps/(core|custom)|content|styles|meta)\.xml$`)
+
+// officeBreaks are the elements ending a line of a document's text, its
+// paragraphs, headings, cells and breaks, and officeSpaces those standing
+// for whitespace.
+var (
+	officeBreaks = map[string]bool{`p`: true, `h`: true, `br`: true, `line-break`: true, `si`: true, `c`: true, `tc`: true, `table-cell`: true}
+	officeSpaces = map[string]bool{`tab`: true, `s`: true}
+)
+
+// officeText returns the text of an Office Open XML or OpenDocument file,
+// a line per paragraph. Text split into runs of different formatting is
+// joined up again, so a notice set partly in bold is found. It fails for
+// anything that isn't a ZIP archive, which is then read as it stands.
+func officeText(b []byte) ([]byte, bool) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, false
+	}
+	var text bytes.Buffer
+	for _, part := range zr.File {
+		if !officeParts.MatchString(part.Name) {
+			continue
+		}
+		rc, err := part.Open()
+		if err != nil {
+			continue
+		}
+		d := xml.NewDecoder(rc)
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				break
+			}
+			switch t := tok.(type) {
+			case xml.CharData:
+				text.Write(t)
+			case xml.StartElement:
+				if officeSpaces[t.Name.Local] {
+					text.WriteByte(' ')
+				}
+			case xml.EndElement:
+				if officeBreaks[t.Name.Local] {
+					text.WriteByte('\n')
+				}
+			}
+		}
+		rc.Close()
+		text.WriteByte('\n')
+	}
+	return text.Bytes(), true
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// offline forbids everything which would touch the network.
+var offline bool
+
+// networkUse names what in the command, target or options would touch the
+// network, or returns the empty string if nothing would.
+func networkUse(command, target string) string {
+	switch {
+	case command == `update-licenses`:
+		return "`weasel update-licenses`"
+	case isObjectURL(target) || isImageURL(target):
+		return "`" + target + "`"
+	case notifyURL != ``:
+		return `--notify-url`
+	case githubCheck:
+		return `--github-check`
+	case signAttestation:
+		return `--sign-attestation`
+	case tracesURL != ``:
+		return `trace export to ` + tracesURL
+	}
+	return ``
+}
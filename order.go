@@ -0,0 +1,53 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sort"
+)
+
+// sortBy orders the rows of the report: by `path`, by `license`, or by
+// `status`, listing errors first. Ties are broken by path.
+var sortBy = `path`
+
+// reverseSort reverses the order of the rows.
+var reverseSort bool
+
+// sortFilenames orders the files as --sort and --reverse ask.
+func sortFilenames(filenames []string, files map[string][]License) {
+	sort.Strings(filenames)
+	if sortBy != `path` {
+		keys := make(map[string]string, len(filenames))
+		for _, name := range filenames {
+			licStr, _, undoc := describe(files[name])
+			switch sortBy {
+			case `license`:
+				keys[name] = licStr
+			case `status`:
+				if !undoc {
+					keys[name] = `ok`
+				}
+			}
+		}
+		sort.SliceStable(filenames, func(i, j int) bool { return keys[filenames[i]] < keys[filenames[j]] })
+	}
+	if reverseSort {
+		for i, j := 0, len(filenames)-1; i < j; i, j = i+1, j-1 {
+			filenames[i], filenames[j] = filenames[j], filenames[i]
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+var outputFormat = `text`
+
+// digests has the report carry the SHA-256 of each file, as JSON output
+// and the statement of --attestation do.
+var digests bool
+
+// reportDigest is the SHA-256 of a file for the report, if it carries
+// them and the file can be read.
+func reportDigest(name string) string {
+	if !digests {
+		return ``
+	}
+	digest, _ := fileDigest(name)
+	return digest
+}
+
+// reproducible leaves out of the report whatever differs between runs over
+// the same files: the directory scanned, and in metrics the time taken.
+var reproducible bool
+
+// schemaVersion is reported in JSON and NDJSON output, and must be bumped
+// whenever jsonSchema changes in a way existing parsers would reject.
+const schemaVersion = `1`
+
+// FileResult is one row of the report.
+type FileResult struct {
+	Path     string     `json:"path"`
+	Licenses []License  `json:"licenses"`
+	Error    bool       `json:"error"`
+	Codes    []string   `json:"codes,omitempty"` /* Of the findings, such as WSL001. */
+	Evidence []Evidence `json:"evidence,omitempty"`
+
+	SuppressedBy []Suppression `json:"suppressedBy,omitempty"`
+	SHA256       string        `json:"sha256,omitempty"`
+
+	scanned string /* The path as scanned, which --paths may print otherwise. */
+}
+
+// Report is the whole of the JSON output.
+type Report struct {
+	SchemaVersion string        `json:"schemaVersion"`
+	Root          string        `json:"root"`
+	Files         []FileResult  `json:"files"`
+	ExtraLicenses []string      `json:"extraLicenses"`
+	Conclusion    string        `json:"conclusion"`
+	Unreadable    int           `json:"unreadable"`
+	Failed        bool          `json:"failed"`
+	Ignored       []Suppression `json:"ignored,omitempty"`
+	Stale         []Suppression `json:"stale,omitempty"`
+	Deprecated    []deprecation `json:"deprecated,omitempty"`
+	Interrupted   bool          `json:"interrupted,omitempty"` /* Only some of the files were scanned. */
+
+	Suppressed []suppressionCount `json:"suppressed"`
+}
+
+// record is one line of the NDJSON output: a `file` per row of the report,
+// an `extra-license` per unused LICENSE entry, and a final `summary`.
+type record struct {
+	SchemaVersion string     `json:"schemaVersion"`
+	Type          string     `json:"type"`
+	Path          string     `json:"path,omitempty"`
+	Licenses      []License  `json:"licenses,omitempty"`
+	Error         bool       `json:"error,omitempty"`
+	Codes         []string   `json:"codes,omitempty"`
+	Evidence      []Evidence `json:"evidence,omitempty"`
+	Root          string     `json:"root,omitempty"`
+	Conclusion    string     `json:"conclusion,omitempty"`
+	Unreadable    int        `json:"unreadable,omitempty"`
+	Failed        bool       `json:"failed,omitempty"`
+
+	SuppressedBy []Suppression `json:"suppressedBy,omitempty"`
+	SHA256       string        `json:"sha256,omitempty"`
+	Ignored      []Suppression `json:"ignored,omitempty"`
+	Stale        []Suppression `json:"stale,omitempty"`
+	Deprecated   []deprecation `json:"deprecated,omitempty"`
+	Interrupted  bool          `json:"interrupted,omitempty"`
+
+	Suppressed []suppressionCount `json:"suppressed,omitempty"`
+}
+
+func newReport(results []FileResult, extra []string, conclusion string, failed bool) Report {
+	root, _ := os.Getwd()
+	if results == nil {
+		results = []FileResult{}
+	}
+	if extra == nil {
+		extra = []string{}
+	}
+	return Report{
+		SchemaVersion: schemaVersion,
+		Root:          root,
+		Files:         results,
+		ExtraLicenses: extra,
+		Conclusion:    conclusion,
+		Failed:        failed,
+	}
+}
+
+func writeJSON(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent(``, `  `)
+	return enc.Encode(r)
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -27,7 +28,10 @@ import (
 var override = make(map[string][]License)
 
 func loadOverrides() {
-	filepath.Walk(".", func(name string, info os.FileInfo, err error) error {
+	approvals = nil
+	staleOverrides = nil
+	takeSuppressions(``)
+	walkTree(".", func(name string, info os.FileInfo, err error) error {
 		if filepath.Base(name) == `.git` {
 			return filepath.SkipDir
 		}
@@ -39,32 +43,37 @@ func loadOverrides() {
 	})
 }
 func loadOverrideFile(overrideFile string) {
-	if _, err := os.Stat(overrideFile); err != nil {
+	if _, err := statFile(overrideFile); err != nil {
 		return
 	}
 
-	f, err := os.Open(overrideFile)
+	f, err := openFile(overrideFile)
 	if err != nil {
 		panic(err)
 	}
 	defer f.Close()
 
-	prefix := filepath.Dir(overrideFile)
+	/* Paths are matched with forward slashes, whatever the platform. */
+	prefix := filepath.ToSlash(filepath.Dir(overrideFile))
 	if prefix == `.` {
 		prefix = ``
 	} else {
-		prefix = regexp.QuoteMeta(prefix + string(filepath.Separator))
+		prefix = regexp.QuoteMeta(prefix + `/`)
 	}
 
 	type licenseFilter struct {
 		License License
 		Regexp  *regexp.Regexp
+		Line    int
+		Entry   string
 	}
 
 	var regexps []licenseFilter
 
 	s := bufio.NewScanner(f)
+	lineNum := 0
 	for s.Scan() {
+		lineNum++
 		line := s.Text()
 		line = strings.TrimSpace(line)
 		if line == `` || line[0] == '#' {
@@ -77,33 +86,53 @@ func loadOverrideFile(overrideFile string) {
 		}
 
 		strRe, lic := strings.Join(parts[:len(parts)-1], `,`), parts[len(parts)-1]
+		comment := ``
 		licParts := strings.SplitN(lic, `#`, 2)
 		if len(licParts) > 1 {
-			lic = licParts[0]
+			lic, comment = licParts[0], licParts[1]
 		}
 		lic = strings.TrimSpace(lic)
 
+		a, err := parseApproval(comment)
+		if err != nil {
+			panic("Malformed line in " + overrideFile + ": " + line + "\n" + err.Error())
+		}
+		a.File, a.Line, a.Pattern, a.License = overrideFile, lineNum, strRe, License(lic)
+		approvals = append(approvals, a)
+		if a.expired() {
+			/* A lapsed approval no longer applies, so the file is checked afresh. */
+			continue
+		}
+
 		if len(strRe) > 0 && strRe[0] == '^' {
 			strRe = `^` + prefix + strRe[1:]
 		} else {
 			strRe = `^` + prefix + ".*" + strRe
 		}
-		re, cmpErr := regexp.Compile(strRe)
+		re, cmpErr := regexp.Compile(nfc(strRe))
 		if cmpErr != nil {
 			panic("Malformed regexp: " + strRe + "\n" + cmpErr.Error())
 		}
 
-		regexps = append(regexps, licenseFilter{License(lic), re})
+		regexps = append(regexps, licenseFilter{License(lic), re, lineNum, a.Pattern + `, ` + lic})
 	}
 
-	err = filepath.Walk(`.`, func(path string, info os.FileInfo, err error) error {
+	matched := make([]bool, len(regexps))
+	err = walkTree(`.`, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			/* Unreadable directories are reported by the scan itself. */
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
-		for _, filter := range regexps {
-			if filter.Regexp.MatchString(path) {
+		slashPath := nfc(filepath.ToSlash(path))
+		for i, filter := range regexps {
+			if filter.Regexp.MatchString(slashPath) {
+				matched[i] = true
 				override[path] = append(override[path], filter.License)
+				recordSuppression(path, Suppression{Kind: `override`, Source: fmt.Sprintf("%s:%d", filepath.ToSlash(overrideFile), filter.Line), Entry: filter.Entry})
 			}
 		}
 
@@ -113,4 +142,10 @@ func loadOverrideFile(overrideFile string) {
 	if err != nil {
 		panic(`Failed when enumerating working directory: ` + err.Error())
 	}
+
+	for i, filter := range regexps {
+		if !matched[i] {
+			staleOverrides = append(staleOverrides, Suppression{Kind: `override`, Source: fmt.Sprintf("%s:%d", filepath.ToSlash(overrideFile), filter.Line), Entry: filter.Entry})
+		}
+	}
 }
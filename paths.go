@@ -0,0 +1,62 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// pathStyle is how the report prints the paths of files: `relative` to the
+// root of the project, `absolute`, or `from-cwd`, relative to the
+// directory weasel was run from.
+var pathStyle = `relative`
+
+// stripPrefix is taken from the start of each path printed, and then
+// pathPrefix put in its place, so that the paths of a shard or a container
+// match the layout of the source.
+var stripPrefix, pathPrefix string
+
+// invokedFrom is the directory weasel was run from.
+var invokedFrom string
+
+// pathBase is the directory the paths of the scanned files are relative
+// to, or nothing when they are not on disk.
+var pathBase string
+
+// displayPath is the path of a file as --paths, --strip-prefix and
+// --path-prefix ask it to be printed.
+func displayPath(name string) string {
+	if pathStyle != `relative` && pathBase != `` {
+		abs := filepath.Join(pathBase, filepath.FromSlash(name))
+		name = filepath.ToSlash(abs)
+		if pathStyle == `from-cwd` {
+			if rel, err := filepath.Rel(invokedFrom, abs); err == nil {
+				name = filepath.ToSlash(rel)
+			}
+		}
+	}
+	/* Only whole directories are stripped: `lib` leaves `library/x.go` be. */
+	if dir := strings.TrimSuffix(stripPrefix, `/`) + `/`; stripPrefix != `` && strings.HasPrefix(name, dir) {
+		name = strings.TrimPrefix(name, dir)
+	}
+	if pathPrefix != `` {
+		name = path.Join(pathPrefix, name)
+	}
+	return name
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"unicode/utf16"
+)
+
+// pdfStream is the dictionary preceding a stream, and the start of its data.
+var pdfStream = regexp.MustCompile(`>>\s*stream\r?\n`)
+
+// pdfSkipped are the keys of the streams which hold no text: fonts, whose
+// programs may bear notices of their own, images and cross-references.
+var pdfSkipped = regexp.MustCompile(`/Length[123]\b|/Subtype\s*/(Image|Type1C|CIDFontType0C|OpenType)\b|/Type\s*/(XRef|ObjStm)\b`)
+
+// pdfFilter is the filters of a stream, alone or in an array.
+var pdfFilter = regexp.MustCompile(`/Filter\s*(/\w+|\[[\s/\w]*\])`)
+
+// pdfMetadata marks the stream of a document's XMP metadata.
+var pdfMetadata = regexp.MustCompile(`/Type\s*/Metadata\b`)
+
+// texLigatures are the codes of the ligatures of TeX's text fonts, which
+// would otherwise break up the words bearing them.
+var texLigatures = map[byte]string{0x0b: `ff`, 0x0c: `fi`, 0x0d: `fl`, 0x0e: `ffi`, 0x0f: `ffl`}
+
+// pdfText returns the text of a PDF document: that shown by its page
+// contents, whether uncompressed or compressed with Flate, and that of its
+// XMP metadata. Text in other encodings, such as the subsets of CID fonts,
+// is not decoded. It fails for anything that doesn't parse as a PDF,
+// which is then read as it stands.
+func pdfText(b []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(b, []byte(`%PDF-`)) {
+		return nil, false
+	}
+	var text bytes.Buffer
+	meta := imageMetadata{seen: make(map[string]bool)}
+	for _, loc := range pdfStream.FindAllIndex(b, -1) {
+		dict := pdfDictionary(b[:loc[0]+2])
+		end := bytes.Index(b[loc[1]:], []byte(`endstream`))
+		if dict == nil || end < 0 || pdfSkipped.Match(dict) {
+			continue
+		}
+		data := b[loc[1] : loc[1]+end]
+		if filter := pdfFilter.FindSubmatch(dict); filter != nil {
+			/* Only Flate is decoded, and only by itself. */
+			if string(bytes.Trim(filter[1], "[] \t\r\n")) != `/FlateDecode` {
+				continue
+			}
+			if data = pdfInflate(data); data == nil {
+				continue
+			}
+		}
+		if pdfMetadata.Match(dict) {
+			meta.xmp(data)
+			continue
+		}
+		pdfContent(data, &text)
+	}
+	text.Write(meta.text.Bytes())
+	return text.Bytes(), true
+}
+
+// pdfDictionary finds the dictionary ending b.
+func pdfDictionary(b []byte) []byte {
+	depth := 0
+	for i := len(b) - 1; i > 0; i-- {
+		if b[i] == '>' && b[i-1] == '>' {
+			depth++
+			i--
+		} else if b[i] == '<' && b[i-1] == '<' {
+			depth--
+			i--
+			if depth == 0 {
+				return b[i:]
+			}
+		}
+	}
+	return nil
+}
+
+// pdfInflate decompresses the data of a FlateDecode stream, yielding what
+// it can of a truncated one, or nothing if it's corrupt.
+func pdfInflate(b []byte) []byte {
+	var data []byte
+	if zr, err := zlib.NewReader(bytes.NewReader(b)); err == nil {
+		data, _ = ioutil.ReadAll(zr)
+		zr.Close()
+	} else {
+		/* Some writers omit the zlib header. */
+		data, _ = ioutil.ReadAll(flate.NewReader(bytes.NewReader(b)))
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// pdfContent writes the strings a content stream shows, a line per
+// positioning of the text, and spaces for wide gaps within a line.
+func pdfContent(b []byte, text *bytes.Buffer) {
+	var shown bytes.Buffer /* What the pending operator would show. */
+	inArray := false
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == '%':
+			for i < len(b) && b[i] != '\n' && b[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			var s []byte
+			s, i = pdfLiteral(b, i+1)
+			shown.Write(s)
+		case c == '<' && i+1 < len(b) && b[i+1] == '<', c == '>' && i+1 < len(b) && b[i+1] == '>':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(b[i:], '>')
+			if end < 0 {
+				return
+			}
+			shown.Write(pdfString(pdfHex(b[i+1 : i+end])))
+			i += end + 1
+		case c == '[':
+			inArray = true
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		case isPDFSpace(c) || c == '{' || c == '}' || c == '/' || c == '>' || c == ')':
+			i++
+		default:
+			start := i
+			for i < len(b) && !isPDFSpace(b[i]) && !bytes.ContainsRune([]byte(`()<>[]{}/%`), rune(b[i])) {
+				i++
+			}
+			word := string(b[start:i])
+			if n, err := strconv.ParseFloat(word, 64); err == nil {
+				/* Kerning wider than a fifth of the font is a space. */
+				if inArray && n < -200 {
+					shown.WriteByte(' ')
+				}
+				continue
+			}
+			if start > 0 && b[start-1] == '/' {
+				continue
+			}
+			switch word {
+			case `Tj`, `TJ`:
+				text.Write(shown.Bytes())
+			case `'`, `"`:
+				text.WriteByte('\n')
+				text.Write(shown.Bytes())
+			case `BT`, `ET`, `Td`, `TD`, `Tm`, `T*`:
+				text.WriteByte('\n')
+			case `ID`:
+				/* The data of an inline image runs up to EI. */
+				end := bytes.Index(b[i:], []byte("EI"))
+				if end < 0 {
+					return
+				}
+				i += end + 2
+			}
+			shown.Reset()
+		}
+	}
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// pdfLiteral decodes the literal string starting at b[i], after its
+// opening parenthesis, returning it and where it ends.
+func pdfLiteral(b []byte, i int) ([]byte, int) {
+	var s []byte
+	depth := 1
+	for ; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '\\' && i+1 < len(b):
+			i++
+			switch e := b[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b', 'f', '\n':
+			case '\r':
+				if i+1 < len(b) && b[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7'; j++ {
+						n = n*8 + int(b[i]-'0')
+						i++
+					}
+					i--
+					s = append(s, byte(n))
+				} else {
+					s = append(s, e)
+				}
+			}
+		case c == '(':
+			depth++
+			s = append(s, c)
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return pdfString(s), i + 1
+			}
+			s = append(s, c)
+		default:
+			s = append(s, c)
+		}
+	}
+	return pdfString(s), i
+}
+
+// pdfHex decodes a hexadecimal string, an odd last digit followed by 0.
+func pdfHex(b []byte) []byte {
+	var digits []byte
+	for _, c := range b {
+		if _, err := strconv.ParseUint(string(c), 16, 8); err == nil {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make([]byte, len(digits)/2)
+	for i := range s {
+		n, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		s[i] = byte(n)
+	}
+	return s
+}
+
+// pdfString decodes the bytes of a string as UTF-16 if they begin with its
+// byte order mark, and otherwise as Latin-1, but for TeX's ligatures.
+func pdfString(b []byte) []byte {
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		units := make([]uint16, (len(b)-2)/2)
+		for i := range units {
+			units[i] = uint16(b[2+2*i])<<8 | uint16(b[3+2*i])
+		}
+		return []byte(string(utf16.Decode(units)))
+	}
+	var s []rune
+	for _, c := range b {
+		if lig, ok := texLigatures[c]; ok {
+			s = append(s, []rune(lig)...)
+		} else {
+			s = append(s, rune(c))
+		}
+	}
+	return []byte(string(s))
+}
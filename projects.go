@@ -0,0 +1,83 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// scanProject scans the project rooted at dir as though weasel had been run
+// there, with its own overrides and LICENSE, returning the results and the
+// unused LICENSE entries. The working directory is restored afterwards.
+func scanProject(dir string) (map[string][]License, []string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return nil, nil, err
+	}
+	defer os.Chdir(cwd)
+
+	override = make(map[string][]License)
+	documented = nil
+	if err := loadProjectConfig(); err != nil {
+		return nil, nil, err
+	}
+	loadOverrides()
+	recordDocumentedLicenses()
+
+	files, err := scan(`.`)
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, documented.Extra(), nil
+}
+
+// scanProjects scans each project in turn and merges the results into one
+// report, each path prefixed with the root it was found under.
+func scanProjects(roots, prefixes []string) (map[string][]License, []string, []Suppression, error) {
+	files := make(map[string][]License)
+	var extras []string
+	var stale []Suppression
+	allSuppressed := make(map[string][]Suppression)
+	var allIgnored []Suppression
+	defer func() { restoreSuppressions(allSuppressed, allIgnored) }()
+	for i, root := range roots {
+		rootFiles, rootExtras, err := scanProject(root)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, s := range staleEntries() {
+			s.Source = filepath.ToSlash(filepath.Join(prefixes[i], s.Source))
+			stale = append(stale, s)
+		}
+		suppressed, ignored := takeSuppressions(prefixes[i])
+		for name, s := range suppressed {
+			allSuppressed[name] = s
+		}
+		allIgnored = append(allIgnored, ignored...)
+		for name, lics := range rootFiles {
+			files[filepath.Join(prefixes[i], name)] = lics
+		}
+		for _, extra := range rootExtras {
+			extras = append(extras, filepath.ToSlash(filepath.Join(prefixes[i], extra)))
+		}
+	}
+	return files, extras, stale, nil
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Reporter receives the results of a scan: Start before the first file,
+// Result for each file not ignored, in order of path, and Summary once
+// every file has been reported. Formats which need the whole report to
+// begin, such as JSON, write it at Summary. It is what the formats of
+// this command implement; as package main, weasel offers it to no other
+// program.
+type Reporter interface {
+	Start(root string) error
+	Result(res FileResult) error
+	Summary(s Summary) error
+}
+
+// Summary is what a Reporter is given at the end: the whole report, and
+// how many of the files reported were unknown.
+type Summary struct {
+	Report
+	Unknown        int
+	Total          int
+	UnknownAllowed bool /* The unknown files are within --max-unknown and --max-unknown-pct. */
+}
+
+// newReporter returns the Reporter for --format. The text reporter needs
+// the licenses of every file for the vendored components' rollup, and
+// prints only errors when quiet.
+func newReporter(w io.Writer, files map[string][]License, quiet bool) Reporter {
+	switch outputFormat {
+	case `text`:
+		return &textReporter{w: w, files: files, quiet: quiet}
+	case `ndjson`:
+		return &ndjsonReporter{enc: json.NewEncoder(w)}
+	case `azdo`:
+		return &batchReporter{w, writeAzDO}
+	case `teamcity`:
+		return &batchReporter{w, writeTeamCity}
+	case `tap`:
+		return &batchReporter{w, writeTAP}
+	case `compact`:
+		return &batchReporter{w, writeCompact}
+	}
+	return &batchReporter{w, writeJSON}
+}
+
+// maxErrors is how many error rows the text and compact formats print
+// before summing up the rest, or -1 for all of them.
+var maxErrors = -1
+
+// textReporter prints a row per file, and the findings concerning the
+// project as a whole at the end. The rows are held until then, so that
+// the columns fit the longest of them.
+type textReporter struct {
+	w     io.Writer
+	files map[string][]License
+	quiet bool
+
+	errors int /* Error rows reported, whether printed or not. */
+	rows   []textRow
+}
+
+// printsError counts an error row, telling whether it is to be printed.
+func (t *textReporter) printsError() bool {
+	t.errors++
+	return maxErrors < 0 || t.errors <= maxErrors
+}
+
+func (t *textReporter) Start(root string) error {
+	return nil
+}
+
+func (t *textReporter) Result(res FileResult) error {
+	if (!res.Error && t.quiet) || reportKind == `heatmap` {
+		return nil
+	}
+	errStr := ""
+	if res.Error {
+		errStr = "Error"
+		if !t.printsError() {
+			return nil
+		}
+	}
+	licStr := make([]string, len(res.Licenses))
+	for i, lic := range res.Licenses {
+		licStr[i] = string(lic)
+	}
+	row := fileRow(errStr, withCodes(strings.Join(licStr, `, `), res.Codes), res.Path, res.scanned)
+	if explain {
+		var below bytes.Buffer
+		printEvidence(&below, res.scanned)
+		printSuppressions(&below, res.scanned)
+		row.below = below.String()
+	}
+	t.rows = append(t.rows, row)
+	return nil
+}
+
+func (t *textReporter) Summary(s Summary) error {
+	for _, extra := range s.ExtraLicenses {
+		if t.printsError() {
+			t.rows = append(t.rows, textRow{cells: map[string]string{`status`: "Error", `licenses`: withCodes("Extra-License!", []string{`WSL003`}), `path`: extra}})
+		}
+	}
+	for _, st := range s.Stale {
+		if t.printsError() {
+			t.rows = append(t.rows, textRow{cells: map[string]string{`status`: "Error", `licenses`: withCodes(staleFinding(st), []string{st.Code}), `path`: st.Source + `: ` + st.Entry}})
+		}
+	}
+	var ignored []textRow
+	if explain && !t.quiet {
+		for _, ig := range s.Ignored {
+			var below bytes.Buffer
+			printSuppression(&below, ig)
+			ignored = append(ignored, textRow{cells: map[string]string{`licenses`: "Ignored", `path`: ig.Path}, below: below.String()})
+		}
+	}
+	var vendors []textRow
+	if vendorPolicy == `report` {
+		vendors = vendorRows(t.files)
+	}
+	fit := widths(append(append(append([]textRow(nil), t.rows...), ignored...), vendors...))
+	for _, row := range t.rows {
+		if err := writeRow(t.w, row, fit); err != nil {
+			return err
+		}
+	}
+	if reportKind == `heatmap` {
+		writeHeatmap(t.w, t.files)
+	}
+	if maxErrors >= 0 && t.errors > maxErrors {
+		fmt.Fprintf(t.w, "... and %d more errors.\n", t.errors-maxErrors)
+	}
+	if s.Unknown > 0 && s.UnknownAllowed && !t.quiet {
+		pct := 100 * float64(s.Unknown) / float64(s.Total)
+		fmt.Fprintf(t.w, "%d unknown files (%.1f%%) are within the allowed limit.\n", s.Unknown, pct)
+	}
+	if s.Unreadable > 0 {
+		fmt.Fprintf(t.w, "%d files could not be read.\n", s.Unreadable)
+	}
+	if !t.quiet {
+		for _, c := range s.Suppressed {
+			fmt.Fprintf(t.w, "%d files suppressed by %s entries of %s.\n", c.Files, c.Kind, c.Source)
+		}
+	}
+	for _, d := range s.Deprecated {
+		fmt.Fprintf(t.w, "%s is a deprecated SPDX identifier, used by %d files; use %s instead.\n", d.ID, len(d.Paths), d.Replacement)
+	}
+	for _, row := range vendors {
+		if err := writeRow(t.w, row, fit); err != nil {
+			return err
+		}
+	}
+	if printConclusion {
+		fmt.Fprintln(t.w, "Repository license: "+s.Conclusion)
+	}
+	for _, row := range ignored {
+		if err := writeRow(t.w, row, fit); err != nil {
+			return err
+		}
+	}
+	if s.Interrupted {
+		fmt.Fprintln(t.w, "Interrupted: this report is of only the files scanned before then.")
+	}
+	return nil
+}
+
+// ndjsonReporter streams a `file` record per file as it is reported, then
+// an `extra-license` record per unused LICENSE entry, and the summary.
+type ndjsonReporter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonReporter) Start(root string) error {
+	return nil
+}
+
+func (n *ndjsonReporter) Result(res FileResult) error {
+	return n.enc.Encode(record{SchemaVersion: schemaVersion, Type: `file`, Path: res.Path, Licenses: res.Licenses, Error: res.Error, Codes: res.Codes, Evidence: res.Evidence, SuppressedBy: res.SuppressedBy, SHA256: res.SHA256})
+}
+
+func (n *ndjsonReporter) Summary(s Summary) error {
+	for _, extra := range s.ExtraLicenses {
+		if err := n.enc.Encode(record{SchemaVersion: s.SchemaVersion, Type: `extra-license`, Path: extra, Error: true, Codes: []string{`WSL003`}}); err != nil {
+			return err
+		}
+	}
+	return n.enc.Encode(record{SchemaVersion: s.SchemaVersion, Type: `summary`, Root: s.Root, Conclusion: s.Conclusion, Unreadable: s.Unreadable, Failed: s.Failed, Ignored: s.Ignored, Stale: s.Stale, Deprecated: s.Deprecated, Interrupted: s.Interrupted, Suppressed: s.Suppressed})
+}
+
+// batchReporter writes the whole report at once, for formats which cannot
+// be streamed.
+type batchReporter struct {
+	w     io.Writer
+	write func(io.Writer, Report) error
+}
+
+func (b *batchReporter) Start(root string) error {
+	return nil
+}
+
+func (b *batchReporter) Result(res FileResult) error {
+	return nil
+}
+
+func (b *batchReporter) Summary(s Summary) error {
+	return b.write(b.w, s.Report)
+}
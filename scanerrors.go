@@ -0,0 +1,92 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// multiError is several errors as one, such as those of every file a scan
+// could not read.
+type multiError []error
+
+func (m multiError) Error() string {
+	if len(m) == 1 {
+		return m[0].Error()
+	}
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(m), strings.Join(msgs, `; `))
+}
+
+// Is tells errors.Is whether any of the errors is target.
+func (m multiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As sets target from the first of the errors errors.As matches.
+func (m multiError) As(target interface{}) bool {
+	for _, err := range m {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// unreadable collects the files and directories which could not be read,
+// met either by the walk or by the goroutine identifying a file. Each is
+// also reported as a finding such as `Error: permission denied!`, but is
+// counted in the summary and exit status from here, whatever becomes of
+// its finding, as when a vendored file's findings are only reported.
+var unreadable = struct {
+	sync.Mutex
+	errs multiError
+}{}
+
+func recordUnreadable(name string, err error) {
+	if _, ok := err.(*os.PathError); !ok {
+		err = &os.PathError{Op: `read`, Path: name, Err: err}
+	}
+	unreadable.Lock()
+	defer unreadable.Unlock()
+	unreadable.errs = append(unreadable.errs, err)
+}
+
+// unreadableErrors returns the errors of the files which could not be
+// read, in order of path.
+func unreadableErrors() multiError {
+	unreadable.Lock()
+	defer unreadable.Unlock()
+	errs := append(multiError(nil), unreadable.errs...)
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].(*os.PathError).Path < errs[j].(*os.PathError).Path
+	})
+	return errs
+}
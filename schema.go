@@ -0,0 +1,163 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// jsonSchema describes the output of `--format json`, and of each line of
+// `--format ndjson`. It is printed by `weasel schema`.
+const jsonSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "weasel report",
+  "definitions": {
+    "schemaVersion": {
+      "description": "Version of this schema the output conforms to.",
+      "const": "1"
+    },
+    "licenses": {
+      "description": "Licenses of the file. A trailing '!' marks a license which is not documented in LICENSE, a trailing '~' one inherited from a LICENSE file in a parent directory.",
+      "type": "array",
+      "items": {"type": "string"}
+    },
+    "sha256": {
+      "description": "SHA-256 of the content of the file, in hex. Absent for files which could not be read.",
+      "type": "string",
+      "pattern": "^[0-9a-f]{64}$"
+    },
+    "evidence": {
+      "description": "What led to each license being identified: the normalized words of a matched phrase and the lines they span, or how much of a custom license's reference text was found.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["license", "phrase"],
+        "properties": {
+          "license": {"type": "string"},
+          "phrase": {"type": "string"},
+          "startLine": {"type": "integer"},
+          "endLine": {"type": "integer"}
+        }
+      }
+    },
+    "suppression": {
+      "description": "A non-default decision: an override, an '@'-line of LICENSE, an ignore pattern of .gitignore or .weasel.yml, or a baseline entry.",
+      "type": "object",
+      "required": ["kind", "source", "entry"],
+      "properties": {
+        "path": {"description": "The path ignored, for ignored paths only.", "type": "string"},
+        "kind": {"enum": ["override", "documented", "gitignore", "ignore", "baseline"]},
+        "source": {"description": "The file holding the entry, and its line if known.", "type": "string"},
+        "entry": {"type": "string"},
+        "code": {"description": "The code of the finding, WSL009, for stale entries only.", "type": "string"}
+      }
+    },
+    "codes": {
+      "description": "Stable codes of the file's findings, such as WSL001 for an undocumented license; 'weasel explain <code>' describes each.",
+      "type": "array",
+      "items": {"type": "string", "pattern": "^WSL[0-9]{3}$"}
+    },
+    "suppressions": {"type": "array", "items": {"$ref": "#/definitions/suppression"}},
+    "suppressed": {
+      "description": "How many files the entries of each kind, in each file holding them, suppressed or left out of the scan.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["kind", "source", "files"],
+        "properties": {
+          "kind": {"enum": ["override", "documented", "gitignore", "ignore", "baseline"]},
+          "source": {"type": "string"},
+          "files": {"type": "integer"}
+        }
+      }
+    },
+    "deprecated": {
+      "description": "Deprecated SPDX identifiers reported with --spdx-ids, what replaces each and the files reported with it.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["id", "replacement", "paths"],
+        "properties": {
+          "id": {"type": "string"},
+          "replacement": {"type": "string"},
+          "paths": {"type": "array", "items": {"type": "string"}}
+        }
+      }
+    },
+    "file": {
+      "type": "object",
+      "required": ["path", "licenses", "error"],
+      "properties": {
+        "path": {"type": "string"},
+        "licenses": {"$ref": "#/definitions/licenses"},
+        "error": {"type": "boolean"},
+        "codes": {"$ref": "#/definitions/codes"},
+        "evidence": {"$ref": "#/definitions/evidence"},
+        "suppressedBy": {"$ref": "#/definitions/suppressions"},
+        "sha256": {"$ref": "#/definitions/sha256"}
+      }
+    },
+    "report": {
+      "type": "object",
+      "required": ["schemaVersion", "root", "files", "extraLicenses", "conclusion", "failed"],
+      "properties": {
+        "schemaVersion": {"$ref": "#/definitions/schemaVersion"},
+        "root": {"type": "string"},
+        "files": {"type": "array", "items": {"$ref": "#/definitions/file"}},
+        "extraLicenses": {
+          "description": "Entries of LICENSE which describe no files, findings WSL003.",
+          "type": "array",
+          "items": {"type": "string"}
+        },
+        "conclusion": {"description": "License of the repository as a whole.", "type": "string"},
+        "unreadable": {"description": "Number of files which could not be read.", "type": "integer"},
+        "failed": {"type": "boolean"},
+        "ignored": {"description": "Paths left out of the scan, and why.", "$ref": "#/definitions/suppressions"},
+        "stale": {"description": "Overrides and ignore patterns which matched nothing.", "$ref": "#/definitions/suppressions"},
+        "deprecated": {"$ref": "#/definitions/deprecated"},
+        "interrupted": {"description": "The scan was stopped early, so only some of the files are reported.", "type": "boolean"},
+        "suppressed": {"$ref": "#/definitions/suppressed"}
+      }
+    },
+    "record": {
+      "description": "One line of NDJSON output.",
+      "type": "object",
+      "required": ["schemaVersion", "type"],
+      "properties": {
+        "schemaVersion": {"$ref": "#/definitions/schemaVersion"},
+        "type": {"enum": ["file", "extra-license", "summary"]},
+        "path": {"type": "string"},
+        "licenses": {"$ref": "#/definitions/licenses"},
+        "error": {"type": "boolean"},
+        "codes": {"$ref": "#/definitions/codes"},
+        "evidence": {"$ref": "#/definitions/evidence"},
+        "root": {"type": "string"},
+        "conclusion": {"type": "string"},
+        "unreadable": {"type": "integer"},
+        "failed": {"type": "boolean"},
+        "suppressedBy": {"$ref": "#/definitions/suppressions"},
+        "sha256": {"$ref": "#/definitions/sha256"},
+        "ignored": {"$ref": "#/definitions/suppressions"},
+        "stale": {"$ref": "#/definitions/suppressions"},
+        "deprecated": {"$ref": "#/definitions/deprecated"},
+        "interrupted": {"type": "boolean"},
+        "suppressed": {"$ref": "#/definitions/suppressed"}
+      }
+    }
+  },
+  "oneOf": [
+    {"$ref": "#/definitions/report"},
+    {"$ref": "#/definitions/record"}
+  ]
+}
+`
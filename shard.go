@@ -0,0 +1,57 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"hash/fnv"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// shardIndex and shardCount select the files of one shard of --shard, the
+// first being 1; shardCount is 0 when the tree is scanned whole.
+var shardIndex, shardCount int
+
+// parseShard reads `<i>/<n>`, for the i-th of n shards.
+func parseShard(s string) (int, int, bool) {
+	parts := strings.SplitN(s, `/`, 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	i, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil || i < 1 || i > n {
+		return 0, 0, false
+	}
+	return i, n, true
+}
+
+// inShard tells whether a file belongs to the shard being scanned. Files
+// are assigned by a hash of their path, so each lands in the same shard
+// on every machine, whatever else the tree holds.
+func inShard(name string) bool {
+	if shardCount == 0 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(filepath.ToSlash(name)))
+	return int(h.Sum32()%uint32(shardCount)) == shardIndex-1
+}
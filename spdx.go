@@ -0,0 +1,321 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"embed"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var useSPDX bool
+
+// spdxLicenseList is the SPDX License List, one short identifier per line.
+//
+//go:embed spdxLicenses.txt
+var spdxLicenseList string
+
+// spdxTexts are the texts bundled of licenses on the SPDX License List,
+// named by identifier, such as `spdx/MIT.txt`.
+//
+//go:embed spdx/*.txt
+var spdxTexts embed.FS
+
+// spdxIDs maps the informal license names used by the matchers onto SPDX
+// short identifiers. Where a matcher cannot tell variants apart, the more
+// restrictive variant is chosen, or a LicenseRef- when SPDX has no single
+// identifier for what was matched.
+var spdxIDs = map[License]string{
+	`Apache`:   `Apache-2.0`,
+	`BSD`:      `BSD-3-Clause`,
+	`GoBSD`:    `BSD-3-Clause`,
+	`MIT`:      `MIT`,
+	`ISC`:      `ISC`,
+	`X11`:      `X11`,
+	`WTFPL`:    `WTFPL`,
+	`OFL`:      `OFL-1.1`,
+	`GPL/LGPL`: `LicenseRef-GPL-or-LGPL`,
+}
+
+// spdxDeprecated maps the deprecated identifiers of the SPDX License List
+// onto the identifiers or expressions which replace them.
+var spdxDeprecated = map[string]string{
+	`AGPL-1.0`:                         `AGPL-1.0-only`,
+	`AGPL-3.0`:                         `AGPL-3.0-only`,
+	`BSD-2-Clause-FreeBSD`:             `BSD-2-Clause-Views`,
+	`BSD-2-Clause-NetBSD`:              `BSD-2-Clause`,
+	`bzip2-1.0.5`:                      `bzip2-1.0.6`,
+	`eCos-2.0`:                         `GPL-2.0-or-later WITH eCos-exception-2.0`,
+	`GFDL-1.1`:                         `GFDL-1.1-only`,
+	`GFDL-1.2`:                         `GFDL-1.2-only`,
+	`GFDL-1.3`:                         `GFDL-1.3-only`,
+	`GPL-1.0`:                          `GPL-1.0-only`,
+	`GPL-1.0+`:                         `GPL-1.0-or-later`,
+	`GPL-2.0`:                          `GPL-2.0-only`,
+	`GPL-2.0+`:                         `GPL-2.0-or-later`,
+	`GPL-2.0-with-autoconf-exception`:  `GPL-2.0-only WITH Autoconf-exception-2.0`,
+	`GPL-2.0-with-bison-exception`:     `GPL-2.0-or-later WITH Bison-exception-2.2`,
+	`GPL-2.0-with-classpath-exception`: `GPL-2.0-only WITH Classpath-exception-2.0`,
+	`GPL-2.0-with-font-exception`:      `GPL-2.0-only WITH Font-exception-2.0`,
+	`GPL-2.0-with-GCC-exception`:       `GPL-2.0-or-later WITH GCC-exception-2.0`,
+	`GPL-3.0`:                          `GPL-3.0-only`,
+	`GPL-3.0+`:                         `GPL-3.0-or-later`,
+	`GPL-3.0-with-autoconf-exception`:  `GPL-3.0-or-later WITH Autoconf-exception-3.0`,
+	`GPL-3.0-with-GCC-exception`:       `GPL-3.0-or-later WITH GCC-exception-3.1`,
+	`LGPL-2.0`:                         `LGPL-2.0-only`,
+	`LGPL-2.0+`:                        `LGPL-2.0-or-later`,
+	`LGPL-2.1`:                         `LGPL-2.1-only`,
+	`LGPL-2.1+`:                        `LGPL-2.1-or-later`,
+	`LGPL-3.0`:                         `LGPL-3.0-only`,
+	`LGPL-3.0+`:                        `LGPL-3.0-or-later`,
+	`Nunit`:                            `zlib-acknowledgement`,
+	`StandardML-NJ`:                    `SMLNJ`,
+	`wxWindows`:                        `GPL-2.0-or-later WITH WxWindows-exception-3.1`,
+}
+
+// SPDX returns the SPDX identifier for the license, preserving any trailing
+// `!` or `~` markers. Names with no SPDX equivalent, such as Docs or Empty,
+// are returned unchanged.
+func (l License) SPDX() License {
+	base, suffix := l.split()
+	if id, ok := spdxIDs[base]; ok {
+		return License(id + suffix)
+	}
+	return l
+}
+
+// split separates the license name from its trailing `!` (undocumented) and
+// `~` (inherited) markers.
+func (l License) split() (License, string) {
+	s := string(l)
+	i := len(s)
+	for i > 0 && (s[i-1] == '!' || s[i-1] == '~') {
+		i--
+	}
+	return License(s[:i]), s[i:]
+}
+
+// spdxName is the name a license on the SPDX License List is reported by:
+// the informal name of the hand-written matchers, if they know it, or else
+// its identifier.
+func spdxName(id string) License {
+	for name, spdx := range spdxIDs {
+		/* BSD-3-Clause is reported as BSD, not the narrower GoBSD. */
+		if spdx == id && name != `GoBSD` {
+			return name
+		}
+	}
+	return License(id)
+}
+
+// spdxTagWord is the word of an `SPDX-License-Identifier:` tag. The word
+// after it is read by spdxIDWord.
+var spdxTagWord = strings.ToLower(stripPunc(`SPDX-License-Identifier:`))
+
+// spdxIDWord normalizes the identifier of a tag keeping the `-`, `.` and
+// `+` of identifiers, so that `GPL-2.0+` is not taken for `GPL-2.0`, but
+// not the punctuation around it, such as the parenthesis of an expression
+// or a full stop.
+func spdxIDWord(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' || r == '+' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+	return strings.TrimRight(s, `.`)
+}
+
+// spdxTagPatterns match an `SPDX-License-Identifier:` tag naming any
+// license on the SPDX License List. Licenses which the hand-written
+// matchers know are reported by their informal names, the rest by their
+// SPDX identifiers. Only the first license of an expression is matched.
+func spdxTagPatterns() []licensePattern {
+	var patterns []licensePattern
+	for _, line := range strings.Split(licenseList(), "\n") {
+		id := strings.TrimSpace(line)
+		if id == `` || strings.HasPrefix(id, `#`) {
+			continue
+		}
+		patterns = append(patterns, licensePattern{
+			words:   []string{spdxTagWord, spdxIDWord(id)},
+			license: spdxName(id),
+		})
+	}
+
+	/* Deprecated tags are found too. */
+	var deprecated []string
+	for id := range spdxDeprecated {
+		deprecated = append(deprecated, id)
+	}
+	sort.Strings(deprecated)
+	for _, id := range deprecated {
+		patterns = append(patterns, licensePattern{
+			words:   []string{spdxTagWord, spdxIDWord(id)},
+			license: License(id),
+		})
+	}
+	return patterns
+}
+
+// spdxFingerprints are those of the texts of licenses on the list, the
+// downloaded ones or else the bundled, reported by the names their tags
+// are, in order of identifier.
+func spdxFingerprints() []fingerprint {
+	texts := licenseTexts()
+	var ids []string
+	for id := range texts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var fps []fingerprint
+	for _, id := range ids {
+		var words []string
+		readWords(bytes.NewReader(texts[id]), func(word string, _ int) bool {
+			words = append(words, word)
+			return true
+		})
+		fps = append(fps, newFingerprint(spdxName(id), words))
+	}
+	return fps
+}
+
+// deprecation is a deprecated SPDX identifier the report names, what
+// replaces it and the files reported with it.
+type deprecation struct {
+	ID          string   `json:"id"`
+	Replacement string   `json:"replacement"`
+	Paths       []string `json:"paths"`
+}
+
+// deprecatedIDs finds the deprecated identifiers among the licenses of
+// the results, which --spdx-ids reports by SPDX identifier.
+func deprecatedIDs(results []FileResult) []deprecation {
+	byID := make(map[string]*deprecation)
+	var ids []string
+	for _, res := range results {
+		for _, lic := range res.Licenses {
+			base, _ := lic.split()
+			replacement, ok := spdxDeprecated[string(base)]
+			if !ok {
+				continue
+			}
+			d := byID[string(base)]
+			if d == nil {
+				d = &deprecation{ID: string(base), Replacement: replacement}
+				byID[string(base)] = d
+				ids = append(ids, string(base))
+			}
+			d.Paths = append(d.Paths, res.Path)
+		}
+	}
+	sort.Strings(ids)
+	var found []deprecation
+	for _, id := range ids {
+		found = append(found, *byID[id])
+	}
+	return found
+}
+
+// spdxListed tells whether id is on the SPDX License List.
+func spdxListed(id string) bool {
+	for _, line := range strings.Split(licenseList(), "\n") {
+		if strings.TrimSpace(line) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// spdxTags holds the licenses each file names in an
+// `SPDX-License-Identifier:` tag.
+var spdxTags = struct {
+	sync.Mutex
+	byName map[string][]License
+}{byName: make(map[string][]License)}
+
+var spdxTagPhrase = spdxTagWord + ` `
+
+func recordSPDXTags(name string, ev []Evidence) {
+	if licenseLike(name) {
+		return
+	}
+	var tags []License
+	for _, e := range ev {
+		if strings.HasPrefix(e.Phrase, spdxTagPhrase) {
+			tags = append(tags, e.License)
+		}
+	}
+	if len(tags) == 0 {
+		return
+	}
+	spdxTags.Lock()
+	defer spdxTags.Unlock()
+	spdxTags.byName[name] = tags
+}
+
+// sameLicense tells whether the license of a tag is the license detected
+// from a text, allowing for the matchers not telling variants apart.
+func sameLicense(tag, text License) bool {
+	if tag == text || tag.SPDX() == text.SPDX() {
+		return true
+	}
+	switch text {
+	case `BSD`, `GoBSD`:
+		return strings.HasPrefix(string(tag), `BSD-`)
+	case `GPL/LGPL`:
+		return strings.HasPrefix(string(tag), `GPL-`) || strings.HasPrefix(string(tag), `LGPL-`)
+	}
+	return strings.HasPrefix(string(tag), string(text)+`-`)
+}
+
+// markSPDXConflicts flags with `SPDX-Conflict!` each file whose tag names
+// a license other than that of the nearest LICENSE file, which is usually
+// a header pasted from elsewhere.
+func markSPDXConflicts(files map[string][]License, nearest func(name string) []License) {
+	spdxTags.Lock()
+	defer spdxTags.Unlock()
+	for name, tags := range spdxTags.byName {
+		licenses, ok := files[name]
+		if !ok || Has(licenses, License(`Ignore`)) {
+			continue
+		}
+		var texts []License
+		for _, lic := range nearest(name) {
+			if base, _ := lic.split(); countable(base) {
+				texts = append(texts, base)
+			}
+		}
+		if len(texts) == 0 {
+			continue
+		}
+	tags:
+		for _, tag := range tags {
+			tag = canonical(name, tag)
+			for _, text := range texts {
+				if sameLicense(tag, text) {
+					continue tags
+				}
+			}
+			files[name] = append(licenses, License(`SPDX-Conflict!`))
+			break
+		}
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// staleOverrides are the .dependency_license lines which matched no file.
+var staleOverrides []Suppression
+
+// staleEntries returns the overrides and ignore patterns of the project
+// which matched nothing in the scan. They are dead exceptions, left
+// behind when the files they were written for went away. The user's
+// weasel.yml serves many projects, so it is left out.
+func staleEntries() []Suppression {
+	used := make(map[Suppression]bool)
+	for _, s := range ignoredPaths() {
+		if s.Kind == `ignore` {
+			used[Suppression{Kind: s.Kind, Source: s.Source, Entry: s.Entry}] = true
+		}
+	}
+
+	configs.Lock()
+	var cfgs []*Config
+	for _, cfg := range configs.byDir {
+		if cfg != nil {
+			cfgs = append(cfgs, cfg)
+		}
+	}
+	configs.Unlock()
+	sort.Slice(cfgs, func(i, j int) bool { return cfgs[i].File < cfgs[j].File })
+
+	stale := append([]Suppression(nil), staleOverrides...)
+	for _, cfg := range cfgs {
+		for _, pattern := range cfg.Ignore {
+			s := Suppression{Kind: `ignore`, Source: filepath.ToSlash(cfg.File), Entry: pattern}
+			if !used[s] {
+				stale = append(stale, s)
+			}
+		}
+	}
+	for i := range stale {
+		stale[i].Code = codeOf(License(staleFinding(stale[i])))
+	}
+	return stale
+}
+
+// staleFinding is how a stale entry is reported.
+func staleFinding(s Suppression) string {
+	if s.Kind == `override` {
+		return `Stale-Override!`
+	}
+	return `Stale-Ignore!`
+}
+
+// staleLocation splits the source of a stale entry into its file and line,
+// which is 1 where the source gives none.
+func staleLocation(s Suppression) (string, int) {
+	if i := strings.LastIndex(s.Source, `:`); i > 0 {
+		if line, err := strconv.Atoi(s.Source[i+1:]); err == nil {
+			return s.Source[:i], line
+		}
+	}
+	return s.Source, 1
+}
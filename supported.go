@@ -0,0 +1,126 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// supportedLicense is a license weasel can detect, with the ways it
+// matches it: exactly, by the phrases of its texts, its SPDX tag or its
+// URLs, and fuzzily, by the shingles of a bundled SPDX text or a custom
+// license's reference text.
+type supportedLicense struct {
+	Name  License  `json:"name"`
+	SPDX  string   `json:"spdx,omitempty"`
+	Exact []string `json:"exact,omitempty"`
+	Fuzzy bool     `json:"fuzzy,omitempty"`
+}
+
+// supportedLicenses lists every license the matchers detect, those of the
+// project's custom licenses included, sorted by name.
+func supportedLicenses() []supportedLicense {
+	byName := make(map[License]*supportedLicense)
+	add := func(lic License, exact string) *supportedLicense {
+		s, ok := byName[lic]
+		if !ok {
+			s = &supportedLicense{Name: lic}
+			if _, deprecated := spdxDeprecated[string(lic)]; deprecated || spdxListed(string(lic)) {
+				s.SPDX = string(lic)
+			} else if id := lic.SPDX(); id != lic {
+				s.SPDX = string(id)
+			}
+			byName[lic] = s
+		}
+		if exact != `` && !contains(s.Exact, exact) {
+			s.Exact = append(s.Exact, exact)
+		}
+		return s
+	}
+
+	for _, source := range []struct {
+		patterns []licensePattern
+		exact    string
+	}{
+		{licensePatterns, `phrase`},
+		{dataLicensePatterns(), `phrase`},
+		{matcherPatterns[len(builtinPatterns):], `phrase`},
+		{spdxTagPatterns(), `tag`},
+		{urlPatterns(), `url`},
+	} {
+		for _, p := range source.patterns {
+			if _, ok := notLicenses[p.license]; !ok {
+				add(p.license, source.exact)
+			}
+		}
+	}
+	for _, fp := range fingerprints {
+		add(fp.license, ``).Fuzzy = true
+	}
+
+	var supported []supportedLicense
+	for _, s := range byName {
+		supported = append(supported, *s)
+	}
+	sort.Slice(supported, func(i, j int) bool {
+		return supported[i].Name < supported[j].Name
+	})
+	return supported
+}
+
+func contains(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+// listLicenses prints the supported licenses, as JSON if asked, returning
+// the exit status.
+func listLicenses(w io.Writer, format string) int {
+	supported := supportedLicenses()
+	if format == `json` {
+		b, err := json.MarshalIndent(supported, ``, `  `)
+		if err != nil {
+			fmt.Fprintln(w, "Cannot list licenses: "+err.Error())
+			return 1
+		}
+		fmt.Fprintln(w, string(b))
+		return 0
+	}
+	for _, s := range supported {
+		spdx := s.SPDX
+		if spdx == `` {
+			spdx = `-`
+		}
+		var matchers []string
+		if len(s.Exact) != 0 {
+			matchers = append(matchers, `exact (`+strings.Join(s.Exact, `, `)+`)`)
+		}
+		if s.Fuzzy {
+			matchers = append(matchers, `fuzzy`)
+		}
+		fmt.Fprintf(w, "%-40s %-40s %s\n", s.Name, spdx, strings.Join(matchers, `, `))
+	}
+	return 0
+}
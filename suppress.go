@@ -0,0 +1,159 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Suppression is a non-default decision about a file: an override, an
+// `@`-line of LICENSE, an ignore pattern or a baseline entry.
+type Suppression struct {
+	Path   string `json:"path,omitempty"` /* Only for ignored paths, which have no row. */
+	Kind   string `json:"kind"`
+	Source string `json:"source"` /* The file holding the entry, and its line if known. */
+	Entry  string `json:"entry"`
+	Code   string `json:"code,omitempty"` /* Only for stale entries, WSL009. */
+}
+
+var suppressions = struct {
+	sync.Mutex
+	byName  map[string][]Suppression
+	ignored []Suppression
+}{byName: make(map[string][]Suppression)}
+
+func recordSuppression(name string, s Suppression) {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	suppressions.byName[name] = append(suppressions.byName[name], s)
+}
+
+// forgetSuppressions drops those recorded of a file after the first n.
+func forgetSuppressions(name string, n int) {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	if len(suppressions.byName[name]) > n {
+		suppressions.byName[name] = suppressions.byName[name][:n]
+	}
+}
+
+// recordIgnored notes a path left out of the scan altogether.
+func recordIgnored(name string, s Suppression) {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	s.Path = filepath.ToSlash(name)
+	suppressions.ignored = append(suppressions.ignored, s)
+}
+
+func suppressionsFor(name string) []Suppression {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	return suppressions.byName[name]
+}
+
+func ignoredPaths() []Suppression {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	return suppressions.ignored
+}
+
+// suppressionCount is how many files the entries of one kind in one file
+// suppressed, to watch suppressions accumulate.
+type suppressionCount struct {
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Files  int    `json:"files"`
+}
+
+// suppressionStats counts the files each kind of suppression, in each file
+// holding such entries, applied to or left out of the scan.
+func suppressionStats() []suppressionCount {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	counts := make(map[suppressionCount]int)
+	count := func(ss []Suppression) {
+		seen := make(map[suppressionCount]bool)
+		for _, s := range ss {
+			source, _ := staleLocation(s)
+			key := suppressionCount{Kind: s.Kind, Source: source}
+			if !seen[key] {
+				seen[key] = true
+				counts[key]++
+			}
+		}
+	}
+	for _, ss := range suppressions.byName {
+		count(ss)
+	}
+	for _, s := range suppressions.ignored {
+		count([]Suppression{s})
+	}
+
+	stats := []suppressionCount{}
+	for key, n := range counts {
+		key.Files = n
+		stats = append(stats, key)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Kind != stats[j].Kind {
+			return stats[i].Kind < stats[j].Kind
+		}
+		return stats[i].Source < stats[j].Source
+	})
+	return stats
+}
+
+// takeSuppressions returns those recorded so far, with their paths
+// prefixed, and forgets them.
+func takeSuppressions(prefix string) (map[string][]Suppression, []Suppression) {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	byName := make(map[string][]Suppression)
+	for name, s := range suppressions.byName {
+		byName[filepath.Join(prefix, name)] = s
+	}
+	var ignored []Suppression
+	for _, s := range suppressions.ignored {
+		s.Path = filepath.ToSlash(filepath.Join(prefix, s.Path))
+		ignored = append(ignored, s)
+	}
+	suppressions.byName = make(map[string][]Suppression)
+	suppressions.ignored = nil
+	return byName, ignored
+}
+
+// restoreSuppressions replaces those recorded.
+func restoreSuppressions(byName map[string][]Suppression, ignored []Suppression) {
+	suppressions.Lock()
+	defer suppressions.Unlock()
+	suppressions.byName = byName
+	suppressions.ignored = ignored
+}
+
+func printSuppressions(w io.Writer, name string) {
+	for _, s := range suppressionsFor(name) {
+		printSuppression(w, s)
+	}
+}
+
+func printSuppression(w io.Writer, s Suppression) {
+	fmt.Fprintf(w, "%46s   from %s: %s\n", s.Kind, s.Source, s.Entry)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// licenseLikeNames are the stems of the names of files holding license
+// texts. They are often large and copied verbatim into every vendored
+// dependency.
+var licenseLikeNames = []string{`LICENSE`, `LICENCE`, `COPYING`, `COPYRIGHT`, `NOTICE`, `OFL`}
+
+// licenseTextExts are the extensions a license text may be written with.
+var licenseTextExts = []string{`.MD`, `.MARKDOWN`, `.TXT`, `.TEXT`, `.RST`, `.ADOC`, `.ASCIIDOC`, `.ORG`, `.HTML`, `.HTM`}
+
+// licenseQualifiers may follow a stem after a `.`, as in `COPYING.LESSER`.
+var licenseQualifiers = []string{`LESSER`, `LIB`, `LGPL`, `GPL`, `APACHE`, `MIT`, `BSD`, `ISC`}
+
+// licenseLike tells whether a file holds a license text by its name: a
+// stem alone, or followed by a text extension, by a `-` and a qualifier
+// such as `LICENSE-MIT` or `LICENSE-2.0.txt`, or by a `.` and a qualifier
+// such as `COPYING.LESSER`. Source files such as `license.go` or
+// `license_check.py` are not.
+func licenseLike(name string) bool {
+	base := strings.ToUpper(filepath.Base(name))
+	for _, ext := range licenseTextExts {
+		if strings.HasSuffix(base, ext) && len(base) > len(ext) {
+			base = strings.TrimSuffix(base, ext)
+			break
+		}
+	}
+	for _, stem := range licenseLikeNames {
+		if !strings.HasPrefix(base, stem) {
+			continue
+		}
+		rest := base[len(stem):]
+		switch {
+		case rest == ``:
+			return true
+		case strings.HasPrefix(rest, `-`) && len(rest) > 1:
+			/* A qualifier may hold a version, but ends in no other extension. */
+			ext := filepath.Ext(rest)
+			return ext == `` || strings.IndexFunc(ext[1:], unicode.IsLetter) < 0
+		case strings.HasPrefix(rest, `.`):
+			for _, q := range licenseQualifiers {
+				if rest[1:] == q {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// identified caches the licenses of license-like files by the layoutHash
+// of their text, so each distinct text is matched only once. Reflowed
+// copies of a text are matched again, as their evidence is on other lines.
+var identified = struct {
+	sync.Mutex
+	byHash map[uint64]identification
+}{byHash: make(map[uint64]identification)}
+
+type identification struct {
+	licenses []License
+	evidence []Evidence
+}
+
+// textHash hashes text with case and runs of whitespace folded, which
+// is far cheaper than splitting it into words for matching. Texts
+// differing only by line endings or reflowing share a hash.
+func textHash(b []byte) uint64 {
+	h := fnv.New64a()
+	fold(h, b, false)
+	return h.Sum64()
+}
+
+// layoutHash is textHash keeping the line breaks of each run of
+// whitespace, so texts sharing it have the same words on the same lines.
+func layoutHash(b []byte) uint64 {
+	h := fnv.New64a()
+	fold(h, b, true)
+	return h.Sum64()
+}
+
+// foldedText is the text textHash hashes, to tell apart texts sharing a
+// hash.
+func foldedText(b []byte) string {
+	var folded bytes.Buffer
+	fold(&folded, b, false)
+	return folded.String()
+}
+
+// fold writes b to w with case and runs of whitespace folded, and with
+// only the line breaks of each run if lines is set.
+func fold(w io.Writer, b []byte, lines bool) {
+	buf := make([]byte, 0, 4096)
+	put := func(c byte) {
+		buf = append(buf, c)
+		if len(buf) == cap(buf) {
+			w.Write(buf)
+			buf = buf[:0]
+		}
+	}
+	/* A run of whitespace is written with the word after it, so it is not lost when buf is flushed. */
+	space, wrote, breaks := false, false, 0
+	for _, c := range b {
+		switch {
+		case c == '\n':
+			breaks++
+			space = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			space = true
+			continue
+		case 'A' <= c && c <= 'Z':
+			c += 'a' - 'A'
+		}
+		if lines && breaks > 0 {
+			for ; breaks > 0; breaks-- {
+				put('\n')
+			}
+		} else if space && wrote {
+			put(' ')
+		}
+		space, wrote, breaks = false, true, 0
+		put(c)
+	}
+	w.Write(buf)
+}
+
+// identifyLicenseLike identifies the text of a license-like file, reusing
+// the result for any earlier file with the same text laid out on the same
+// lines.
+func identifyLicenseLike(in io.Reader) ([]License, []Evidence, error) {
+	b, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := layoutHash(b)
+	identified.Lock()
+	id, ok := identified.byHash[key]
+	identified.Unlock()
+	if ok {
+		countCacheHit()
+		return append([]License(nil), id.licenses...), id.evidence, nil
+	}
+
+	licenses, evidence, err := identifyEvidence(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	identified.Lock()
+	identified.byHash[key] = identification{append([]License(nil), licenses...), evidence}
+	identified.Unlock()
+	return licenses, evidence, nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import "testing"
+
+func TestLicenseLike(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{`LICENSE`, true},
+		{`vendor/github.com/x/y/LICENSE`, true},
+		{`LICENCE`, true},
+		{`COPYING`, true},
+		{`NOTICE`, true},
+		{`license.txt`, true},
+		{`License.md`, true},
+		{`LICENSE.rst`, true},
+		{`LICENSE-MIT`, true},
+		{`LICENSE-2.0.txt`, true},
+		{`LICENSE-APACHE-2.0`, true},
+		{`COPYING.LESSER`, true},
+		{`COPYING.LIB`, true},
+		{`OFL.txt`, true},
+		{`license.go`, false},
+		{`license_check.py`, false},
+		{`licenses.json`, false},
+		{`LICENSE-MIT.go`, false},
+		{`LICENSE.go`, false},
+		{`COPYRIGHTS`, false},
+		{`NOTICES.c`, false},
+		{`noticeboard.txt`, false},
+		{`OFLoader.java`, false},
+		{`.txt`, false},
+		{`LICENSE-`, false},
+	}
+	for _, tc := range tests {
+		if got := licenseLike(tc.name); got != tc.want {
+			t.Errorf("licenseLike(%q) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// openFiles bounds how many files are open for identification at once.
+// A goroutine is spawned per file, so without it a large tree exhausts the
+// default limit of 256 or 1024 descriptors.
+var openFiles = make(chan struct{}, 128)
+
+// throttledFile is a file holding one of openFiles until it is closed, or
+// until the identification of its owner, the file it is read for, is
+// abandoned.
+type throttledFile struct {
+	fs.File
+	owner   string
+	release sync.Once
+}
+
+// errAbandoned refuses the files an abandoned identification would open.
+var errAbandoned = errors.New("identification abandoned")
+
+// held are the throttled files open, by owner, the owners whose
+// identification was abandoned, and those awaiting a slot for their first.
+var held = struct {
+	sync.Mutex
+	byOwner   map[string][]*throttledFile
+	abandoned map[string]bool
+	awaiting  map[string]chan struct{}
+}{byOwner: make(map[string][]*throttledFile), abandoned: make(map[string]bool), awaiting: make(map[string]chan struct{})}
+
+// awaitSlot returns a channel closed once the identification of owner has
+// a slot of openFiles, so that --file-timeout needn't count the wait, and
+// a func to stop awaiting it.
+func awaitSlot(owner string) (<-chan struct{}, func()) {
+	ch := make(chan struct{})
+	held.Lock()
+	held.awaiting[owner] = ch
+	held.Unlock()
+	return ch, func() {
+		held.Lock()
+		defer held.Unlock()
+		if held.awaiting[owner] == ch {
+			delete(held.awaiting, owner)
+		}
+	}
+}
+
+// openThrottled opens name for the identification of owner, which is
+// name itself unless owner refers to it.
+func openThrottled(owner, name string) (*throttledFile, error) {
+	tf := &throttledFile{owner: owner}
+	openFiles <- struct{}{}
+	held.Lock()
+	if held.abandoned[owner] {
+		held.Unlock()
+		<-openFiles
+		return nil, errAbandoned
+	}
+	held.byOwner[owner] = append(held.byOwner[owner], tf)
+	if ch, ok := held.awaiting[owner]; ok {
+		close(ch)
+		delete(held.awaiting, owner)
+	}
+	held.Unlock()
+
+	/* Opening a file may block, so the slot is held, and may be given up, meanwhile. */
+	f, err := openFile(name)
+	if err != nil {
+		tf.giveUp()
+		return nil, err
+	}
+	tf.File = f
+	return tf, nil
+}
+
+// giveUp returns the file's slot of openFiles, once.
+func (f *throttledFile) giveUp() {
+	f.release.Do(func() {
+		held.Lock()
+		files := held.byOwner[f.owner]
+		for i, other := range files {
+			if other == f {
+				files = append(files[:i], files[i+1:]...)
+				break
+			}
+		}
+		if len(files) == 0 {
+			delete(held.byOwner, f.owner)
+		} else {
+			held.byOwner[f.owner] = files
+		}
+		held.Unlock()
+		<-openFiles
+	})
+}
+
+// abandonFiles gives up the slots the identification of owner holds, as
+// its goroutine is left to finish or block, and refuses it any others.
+func abandonFiles(owner string) {
+	held.Lock()
+	held.abandoned[owner] = true
+	delete(held.awaiting, owner)
+	files := append([]*throttledFile(nil), held.byOwner[owner]...)
+	held.Unlock()
+	for _, f := range files {
+		f.giveUp()
+	}
+}
+
+// forgetAbandoned forgets the owners abandoned in an earlier scan, as its
+// names are relative to another project, or another commit, and would
+// refuse the files of the same names in this one.
+func forgetAbandoned() {
+	held.Lock()
+	held.abandoned = make(map[string]bool)
+	held.Unlock()
+}
+
+// Seek seeks the file, if its filesystem allows.
+func (f *throttledFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, errors.New("file cannot seek")
+}
+
+func (f *throttledFile) Close() error {
+	err := f.File.Close()
+	f.giveUp()
+	return err
+}
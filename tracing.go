@@ -0,0 +1,199 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tracesURL is where spans are exported using OTLP over HTTP with JSON
+// encoding, configured by the standard OpenTelemetry environment
+// variables. Tracing is off when it is empty.
+var tracesURL = func() string {
+	if url := os.Getenv(`OTEL_EXPORTER_OTLP_TRACES_ENDPOINT`); url != `` {
+		return url
+	}
+	if url := os.Getenv(`OTEL_EXPORTER_OTLP_ENDPOINT`); url != `` {
+		return strings.TrimSuffix(url, `/`) + `/v1/traces`
+	}
+	return ``
+}()
+
+/* spanBatch is how many spans are exported in each request. */
+const spanBatch = 1000
+
+// span is an OpenTelemetry span. A nil span, as returned when tracing is
+// off, does nothing.
+type span struct {
+	traceID, spanID, parentID string
+	name                      string
+	start, end                time.Time
+	attrs                     map[string]string
+}
+
+var spans = struct {
+	sync.Mutex
+	done []*span
+}{}
+
+/* scanSpan is the span of the scan in progress, the parent of its passes. */
+var scanSpan *span
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startSpan begins a span, a root span if parent is nil.
+func startSpan(name string, parent *span) *span {
+	if tracesURL == `` {
+		return nil
+	}
+	s := &span{spanID: randomID(8), name: name, start: time.Now(), attrs: make(map[string]string)}
+	if parent != nil {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		s.traceID = randomID(16)
+	}
+	return s
+}
+
+func (s *span) set(key, value string) {
+	if s != nil {
+		s.attrs[key] = value
+	}
+}
+
+func (s *span) finish() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	spans.Lock()
+	spans.done = append(spans.done, s)
+	spans.Unlock()
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+}
+
+func otlpRequest(batch []*span) interface{} {
+	service := os.Getenv(`OTEL_SERVICE_NAME`)
+	if service == `` {
+		service = `weasel`
+	}
+	var out []otlpSpan
+	for _, s := range batch {
+		o := otlpSpan{
+			TraceID:           s.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              1, /* SPAN_KIND_INTERNAL */
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		for k, v := range s.attrs {
+			o.Attributes = append(o.Attributes, otlpAttribute{k, otlpValue{v}})
+		}
+		out = append(out, o)
+	}
+	return map[string]interface{}{
+		`resourceSpans`: []interface{}{map[string]interface{}{
+			`resource`: map[string]interface{}{
+				`attributes`: []otlpAttribute{{`service.name`, otlpValue{service}}},
+			},
+			`scopeSpans`: []interface{}{map[string]interface{}{
+				`scope`: map[string]string{`name`: `weasel`},
+				`spans`: out,
+			}},
+		}},
+	}
+}
+
+// exportSpans sends the finished spans to the collector.
+func exportSpans() error {
+	if tracesURL == `` {
+		return nil
+	}
+	spans.Lock()
+	done := spans.done
+	spans.done = nil
+	spans.Unlock()
+
+	client := http.Client{Timeout: 30 * time.Second}
+	for len(done) > 0 {
+		n := len(done)
+		if n > spanBatch {
+			n = spanBatch
+		}
+		body, err := json.Marshal(otlpRequest(done[:n]))
+		if err != nil {
+			return err
+		}
+		done = done[n:]
+
+		req, err := http.NewRequest(`POST`, tracesURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set(`Content-Type`, `application/json`)
+		/* OTEL_EXPORTER_OTLP_HEADERS is a list of key=value pairs. */
+		for _, header := range strings.Split(os.Getenv(`OTEL_EXPORTER_OTLP_HEADERS`), `,`) {
+			if kv := strings.SplitN(header, `=`, 2); len(kv) == 2 {
+				req.Header.Set(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+			}
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return errors.New(tracesURL + ": " + resp.Status)
+		}
+	}
+	return nil
+}
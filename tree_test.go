@@ -0,0 +1,64 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// inTree writes the files, by slash-separated name, into a new directory,
+// which is the working directory for the rest of the test. No user config
+// applies, and the configs of earlier tests are forgotten.
+func inTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	userDir, hadUserDir := os.LookupEnv(`WEASEL_CONFIG_DIR`)
+	os.Setenv(`WEASEL_CONFIG_DIR`, t.TempDir())
+	t.Cleanup(func() {
+		os.Chdir(cwd)
+		if hadUserDir {
+			os.Setenv(`WEASEL_CONFIG_DIR`, userDir)
+		} else {
+			os.Unsetenv(`WEASEL_CONFIG_DIR`)
+		}
+		loadProjectConfig()
+	})
+	if err := loadProjectConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// triagePage is how many lines of a file are shown at a time.
+const triagePage = 20
+
+// triageDecision is the override chosen for a file.
+type triageDecision struct {
+	Name    string
+	License License
+	Comment string
+}
+
+// triage presents each unknown or undocumented file in turn, reading from
+// in what to do with it: assign a license, optionally followed by the
+// comment of its override, ignore it, skip it or stop. The decisions are
+// appended to the root .dependency_license. It returns the exit status.
+func triage(in io.Reader, w io.Writer, files map[string][]License) int {
+	var names []string
+	for name, lics := range files {
+		licStr, ignore, undoc := describe(lics)
+		if !ignore && undoc && !isReadError(licStr) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		fmt.Fprintln(w, "Nothing to triage.")
+		return 0
+	}
+
+	s := bufio.NewScanner(in)
+	var decisions []triageDecision
+	quit := false
+	for i, name := range names {
+		licStr, _, _ := describe(files[name])
+		fmt.Fprintf(w, "\n[%d/%d] %s: %s\n", i+1, len(names), filepath.ToSlash(name), licStr)
+		lines, binary := fileHead(name)
+		shown := 0
+		if binary {
+			fmt.Fprintln(w, "    (binary file)")
+		} else {
+			shown = showLines(w, lines, shown)
+		}
+		for {
+			prompt := "License [# comment], (i)gnore, (s)kip or (q)uit? "
+			if shown < len(lines) {
+				prompt = "License [# comment], (m)ore, (i)gnore, (s)kip or (q)uit? "
+			}
+			fmt.Fprint(w, prompt)
+			if !s.Scan() {
+				fmt.Fprintln(w)
+				quit = true
+				break
+			}
+			answer := strings.TrimSpace(s.Text())
+			if answer == `m` && shown < len(lines) {
+				shown = showLines(w, lines, shown)
+				continue
+			}
+			switch answer {
+			case `q`:
+				quit = true
+			case ``, `s`:
+			case `i`:
+				decisions = append(decisions, triageDecision{Name: name, License: License(`Ignore`)})
+			default:
+				lic, comment := answer, ``
+				if parts := strings.SplitN(answer, `#`, 2); len(parts) > 1 {
+					lic, comment = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+				}
+				if lic == `` || strings.Contains(answer, `,`) {
+					fmt.Fprintln(w, "A license may not be empty, nor contain a comma!")
+					continue
+				}
+				decisions = append(decisions, triageDecision{Name: name, License: License(lic), Comment: comment})
+			}
+			break
+		}
+		if quit {
+			break
+		}
+	}
+
+	if len(decisions) == 0 {
+		fmt.Fprintln(w, "No decisions to record.")
+		return 0
+	}
+	if err := recordDecisions(`.dependency_license`, decisions); err != nil {
+		fmt.Fprintln(w, "Cannot record decisions: "+err.Error())
+		return 1
+	}
+	fmt.Fprintf(w, "Recorded %d decisions in .dependency_license.\n", len(decisions))
+	return 0
+}
+
+// fileHead returns the lines at the start of a file, and whether it is
+// binary rather than text.
+func fileHead(name string) ([]string, bool) {
+	f, err := openFile(name)
+	if err != nil {
+		return []string{`(` + err.Error() + `)`}, false
+	}
+	defer f.Close()
+	b, _ := ioutil.ReadAll(io.LimitReader(f, 64*1024))
+	if bytes.IndexByte(b, 0) >= 0 || !utf8.Valid(b) {
+		return nil, true
+	}
+	if len(b) == 0 {
+		return []string{`(empty file)`}, false
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"), false
+}
+
+// showLines prints the next page of lines after those shown, returning how
+// many have now been shown.
+func showLines(w io.Writer, lines []string, shown int) int {
+	end := shown + triagePage
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for _, line := range lines[shown:end] {
+		fmt.Fprintln(w, "    "+strings.TrimRight(line, "\r"))
+	}
+	return end
+}
+
+// recordDecisions appends an override for each decision to the overrides
+// file, matching exactly the file decided upon.
+func recordDecisions(overrideFile string, decisions []triageDecision) error {
+	var buf bytes.Buffer
+	if b, err := readFile(overrideFile); err == nil && len(b) > 0 {
+		if b[len(b)-1] != '\n' {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("# Decided by weasel triage.\n")
+	for _, d := range decisions {
+		line := `^` + regexp.QuoteMeta(filepath.ToSlash(d.Name)) + `$, ` + string(d.License)
+		if d.Comment != `` {
+			line += ` # ` + d.Comment
+		}
+		buf.WriteString(line + "\n")
+	}
+
+	f, err := os.OpenFile(overrideFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spdxListURL is the machine-readable SPDX License List, as published by
+// the license-list-data project.
+var spdxListURL = `https://raw.githubusercontent.com/spdx/license-list-data/main/json/licenses.json`
+
+// licenseCacheFile is where `weasel update-licenses` keeps the list it
+// downloaded, which is preferred over the embedded one.
+func licenseCacheFile() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return ``, err
+	}
+	return filepath.Join(dir, `spdxLicenses.txt`), nil
+}
+
+// licenseTextsDir is where `weasel update-licenses` keeps the texts of the
+// licenses on the list, as `<id>.txt`, which are preferred over the
+// bundled ones.
+func licenseTextsDir() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return ``, err
+	}
+	return filepath.Join(dir, `spdx`), nil
+}
+
+// licenseTexts returns the texts of licenses on the SPDX License List by
+// identifier: those downloaded, and the bundled ones of the rest.
+func licenseTexts() map[string][]byte {
+	texts := make(map[string][]byte)
+	entries, _ := spdxTexts.ReadDir(`spdx`)
+	for _, entry := range entries {
+		if b, err := spdxTexts.ReadFile(`spdx/` + entry.Name()); err == nil {
+			texts[strings.TrimSuffix(entry.Name(), `.txt`)] = b
+		}
+	}
+	if dir, err := licenseTextsDir(); err == nil {
+		infos, _ := ioutil.ReadDir(dir)
+		for _, info := range infos {
+			if !strings.HasSuffix(info.Name(), `.txt`) {
+				continue
+			}
+			if b, err := ioutil.ReadFile(filepath.Join(dir, info.Name())); err == nil {
+				texts[strings.TrimSuffix(info.Name(), `.txt`)] = b
+			}
+		}
+	}
+	return texts
+}
+
+// spdxTextBase is where the texts are downloaded from: beside the list, if
+// it is at `json/licenses.json` of a mirror of license-list-data, or else
+// from the project itself.
+func spdxTextBase() string {
+	if strings.HasSuffix(spdxListURL, `json/licenses.json`) {
+		return strings.TrimSuffix(spdxListURL, `json/licenses.json`) + `text/`
+	}
+	return spdxTextURL
+}
+
+// licenseList returns the downloaded SPDX License List if there is one,
+// or the embedded one.
+func licenseList() string {
+	if name, err := licenseCacheFile(); err == nil {
+		if b, err := ioutil.ReadFile(name); err == nil {
+			return string(b)
+		}
+	}
+	return spdxLicenseList
+}
+
+// updateLicenses downloads the latest SPDX License List, and the text of
+// each license on it, into the cache.
+func updateLicenses(w io.Writer) error {
+	name, err := licenseCacheFile()
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: time.Minute}
+	resp, err := client.Get(spdxListURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(spdxListURL + ": " + resp.Status)
+	}
+
+	var list struct {
+		Version  string `json:"licenseListVersion"`
+		Licenses []struct {
+			ID         string `json:"licenseId"`
+			Deprecated bool   `json:"isDeprecatedLicenseId"`
+		} `json:"licenses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return errors.New(spdxListURL + ": " + err.Error())
+	}
+
+	var ids []string
+	for _, lic := range list.Licenses {
+		if !lic.Deprecated && lic.ID != `` {
+			ids = append(ids, lic.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return errors.New(spdxListURL + ": no licenses listed")
+	}
+
+	text := "# The short identifiers of the SPDX License List, version " + list.Version + ",\n" +
+		"# from " + spdxListURL + ".\n" +
+		strings.Join(ids, "\n") + "\n"
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	/* Written aside and renamed, so a scan never reads half a list. */
+	tmp := name + `.tmp`
+	if err := ioutil.WriteFile(tmp, []byte(text), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		return err
+	}
+
+	dir, err := licenseTextsDir()
+	if err != nil {
+		return err
+	}
+	texts, err := updateTexts(w, dir, ids)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Updated %s to SPDX License List %s (%d licenses, %d texts).\n", name, list.Version, len(ids), texts)
+	return nil
+}
+
+// textWorkers is how many texts are downloaded at once.
+const textWorkers = 8
+
+// updateTexts downloads the text of each license into dir, replacing those
+// of the last update, and returns how many it wrote. A license whose text
+// cannot be downloaded is left to its bundled text, if any.
+func updateTexts(w io.Writer, dir string, ids []string) (int, error) {
+	tmp := dir + `.tmp`
+	if err := os.RemoveAll(tmp); err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return 0, err
+	}
+
+	base := spdxTextBase()
+	var lock sync.Mutex
+	var failed []string
+	written := 0
+	next := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < textWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range next {
+				text, err := fetchText(base, id)
+				if err == nil {
+					err = ioutil.WriteFile(filepath.Join(tmp, id+`.txt`), []byte(text), 0644)
+				}
+				lock.Lock()
+				if err != nil {
+					failed = append(failed, "Cannot download "+id+": "+err.Error())
+				} else {
+					written++
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, id := range ids {
+		/* The identifiers name files, so none may reach outside dir. */
+		if strings.ContainsAny(id, `/\:`) || strings.HasPrefix(id, `.`) {
+			continue
+		}
+		next <- id
+	}
+	close(next)
+	wg.Wait()
+	sort.Strings(failed)
+	for _, msg := range failed {
+		fmt.Fprintln(w, msg)
+	}
+
+	/* Swapped in whole, so a scan never reads the texts of two updates. */
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return 0, err
+	}
+	return written, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// licenseURLs are the canonical addresses of license texts, without their
+// scheme, for headers which only refer to a license by URL. The pages of
+// spdx.org/licenses are added for every license on the SPDX list.
+var licenseURLs = map[string]License{
+	`www.apache.org/licenses/LICENSE-2.0`:        `Apache`,
+	`opensource.org/licenses/Apache-2.0`:         `Apache`,
+	`opensource.org/licenses/MIT`:                `MIT`,
+	`opensource.org/licenses/mit-license.php`:    `MIT`,
+	`opensource.org/licenses/BSD-3-Clause`:       `BSD`,
+	`opensource.org/licenses/BSD-2-Clause`:       `BSD-2-Clause`,
+	`opensource.org/licenses/ISC`:                `ISC`,
+	`www.gnu.org/licenses/gpl-2.0`:               `GPL/LGPL`,
+	`www.gnu.org/licenses/gpl-3.0`:               `GPL/LGPL`,
+	`www.gnu.org/licenses/lgpl-2.1`:              `GPL/LGPL`,
+	`www.gnu.org/licenses/lgpl-3.0`:              `GPL/LGPL`,
+	`www.gnu.org/licenses/old-licenses/gpl-2.0`:  `GPL/LGPL`,
+	`www.gnu.org/licenses/old-licenses/lgpl-2.1`: `GPL/LGPL`,
+	`mozilla.org/MPL/2.0`:                        `MPL-2.0`,
+	`www.eclipse.org/legal/epl-2.0`:              `EPL-2.0`,
+	`www.eclipse.org/legal/epl-v10`:              `EPL-1.0`,
+	`creativecommons.org/licenses/by/4.0`:        `CC-BY-4.0`,
+	`creativecommons.org/licenses/by-sa/4.0`:     `CC-BY-SA-4.0`,
+	`creativecommons.org/publicdomain/zero/1.0`:  `CC0-1.0`,
+	`opendatacommons.org/licenses/odbl`:          `ODbL-1.0`,
+	`opendatacommons.org/licenses/odbl/1-0`:      `ODbL-1.0`,
+	`opendatacommons.org/licenses/by`:            `ODC-By-1.0`,
+	`opendatacommons.org/licenses/by/1-0`:        `ODC-By-1.0`,
+	`opendatacommons.org/licenses/pddl`:          `PDDL-1.0`,
+	`opendatacommons.org/licenses/pddl/1-0`:      `PDDL-1.0`,
+	`www.boost.org/LICENSE_1_0.txt`:              `BSL-1.0`,
+	`unlicense.org`:                              `Unlicense`,
+	`www.wtfpl.net`:                              `WTFPL`,
+	`openfontlicense.org`:                        `OFL`,
+	`scripts.sil.org/OFL`:                        `OFL`,
+}
+
+// urlPatterns match license URLs, with or without a scheme or `www.`, and
+// with the `.txt` and `.html` endings some are linked with. Punctuation is
+// stripped along with the rest of a file's, so each is a single word.
+func urlPatterns() []licensePattern {
+	urls := make(map[string]License)
+	for url, lic := range licenseURLs {
+		urls[url] = lic
+	}
+	for _, p := range spdxTagPatterns() {
+		/* The informal names map back to SPDX identifiers. */
+		id := string(p.license)
+		if spdx, ok := spdxIDs[p.license]; ok {
+			id = spdx
+		}
+		/* A URL is a single word without its punctuation, and that of `GPL-2.0+` is that of `GPL-2.0`. */
+		if strings.HasSuffix(id, `+`) {
+			continue
+		}
+		urls[`spdx.org/licenses/`+id] = p.license
+	}
+
+	/* In order, so that the evidence of a file is the same on every run. */
+	var sorted []string
+	for url := range urls {
+		sorted = append(sorted, url)
+	}
+	sort.Strings(sorted)
+
+	var patterns []licensePattern
+	for _, url := range sorted {
+		lic := urls[url]
+		hosts := []string{url}
+		if strings.HasPrefix(url, `www.`) {
+			hosts = append(hosts, strings.TrimPrefix(url, `www.`))
+		} else {
+			hosts = append(hosts, `www.`+url)
+		}
+		for _, host := range hosts {
+			for _, scheme := range []string{``, `http://`, `https://`} {
+				for _, suffix := range []string{``, `.txt`, `.html`} {
+					patterns = append(patterns, licensePattern{
+						words:   makeWords(scheme + host + suffix),
+						license: lic,
+					})
+				}
+			}
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// vendorPolicy decides what is required of vendored files. With `document`
+// they must be documented like any other file; with `report` they are only
+// summarised, per vendored package, after the results.
+var vendorPolicy = `document`
+
+var vendorDirs = map[string]struct{}{
+	`third_party`:  {},
+	`vendor`:       {},
+	`external`:     {},
+	`node_modules`: {},
+}
+
+var vendorSuffixes = []string{`.min.js`, `.min.css`}
+
+// vendoredRoot returns the vendored package containing the file, such as
+// `node_modules/left-pad`, or the empty string if the file isn't vendored.
+func vendoredRoot(name string) string {
+	parts := strings.Split(filepath.ToSlash(name), `/`)
+	for i, part := range parts[:len(parts)-1] {
+		if _, ok := vendorDirs[part]; ok {
+			end := i + 2
+			if end == len(parts) {
+				end--
+			}
+			return strings.Join(parts[:end], `/`)
+		}
+	}
+	for _, suffix := range vendorSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return filepath.ToSlash(name)
+		}
+	}
+	return ``
+}
+
+func Vendored(name string) bool {
+	return vendoredRoot(name) != ``
+}
+
+// vendorRows are the rows of the licenses found within each vendored
+// package, printed among those of the text report.
+func vendorRows(files map[string][]License) []textRow {
+	roots := make(map[string][]License)
+	for name, lics := range files {
+		root := vendoredRoot(name)
+		if root == `` || Has(lics, License(`Ignore`)) {
+			continue
+		}
+		if _, ok := roots[root]; !ok {
+			roots[root] = nil
+		}
+		for _, lic := range lics {
+			base, _ := lic.split()
+			if base == `Vendored` {
+				continue
+			}
+			if useSPDX {
+				base = base.SPDX()
+			}
+			roots[root] = append(roots[root], base)
+		}
+	}
+
+	var rootNames []string
+	for root := range roots {
+		rootNames = append(rootNames, root)
+	}
+	sort.Strings(rootNames)
+
+	var rows []textRow
+	for _, root := range rootNames {
+		var licStrs []string
+		for _, lic := range Uniq(roots[root]) {
+			licStrs = append(licStrs, string(lic))
+		}
+		licStr := strings.Join(licStrs, `, `)
+		if licStr == `` {
+			licStr = `Unknown`
+		}
+		rows = append(rows, textRow{cells: map[string]string{`status`: "Vendor", `licenses`: licStr, `path`: root}})
+	}
+	return rows
+}
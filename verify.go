@@ -0,0 +1,94 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// verifySBOM reports each file whose licenses differ from those the SPDX
+// document lists for it, those it does not list, and those it lists which
+// are no longer there, returning 1 if there are any.
+func verifySBOM(w io.Writer, doc *spdxDocument, files map[string][]License) int {
+	listed := make(map[string][]string)
+	for _, file := range doc.files {
+		var fileName string
+		if err := json.Unmarshal(file[`fileName`], &fileName); err != nil {
+			continue
+		}
+		ids := []string{`NOASSERTION`}
+		if raw, ok := file[`licenseInfoInFiles`]; ok {
+			json.Unmarshal(raw, &ids)
+		}
+		sort.Strings(ids)
+		listed[strings.TrimPrefix(path.Clean(fileName), `/`)] = ids
+	}
+
+	/* The document is no file of the project. */
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, sbomInput); err == nil {
+			delete(files, rel)
+		}
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, filepath.ToSlash(name))
+	}
+	for name := range listed {
+		if _, ok := files[filepath.FromSlash(name)]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	drifted, total := 0, 0
+	for _, name := range names {
+		lics, scanned := files[filepath.FromSlash(name)]
+		if scanned && Has(reported(lics), License(`Ignore`)) {
+			continue
+		}
+		total++
+		ids, ok := listed[name]
+		var finding string
+		switch {
+		case !scanned:
+			finding = "SBOM: " + strings.Join(ids, ` `) + ", no such file"
+		case !ok:
+			finding = strings.Join(licenseInfoInFile(lics), ` `) + ", not in SBOM"
+		case strings.Join(licenseInfoInFile(lics), ` `) != strings.Join(ids, ` `):
+			finding = strings.Join(licenseInfoInFile(lics), ` `) + ", SBOM: " + strings.Join(ids, ` `)
+		default:
+			continue
+		}
+		drifted++
+		fmt.Fprintf(w, "%-6s%40s %s\n", "Error", finding, displayPath(name))
+	}
+	if drifted > 0 {
+		fmt.Fprintf(w, "%d of %d files disagree with %s.\n", drifted, total, sbomInput)
+		return 1
+	}
+	fmt.Fprintf(w, "%s agrees with all %d files.\n", sbomInput, total)
+	return 0
+}
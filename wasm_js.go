@@ -0,0 +1,67 @@
+//go:build js && wasm
+// +build js,wasm
+
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"syscall/js"
+)
+
+// serveJS exports identifyLicenses to JavaScript, in place of the command
+// line a browser lacks, and serves calls of it until the page goes away.
+//
+// identifyLicenses(text) returns {licenses, evidence}: the licenses the
+// matchers find in text, by their SPDX identifiers if `spdx` is true, and
+// the phrases which identified them with their lines, as in the JSON
+// report.
+func serveJS() {
+	js.Global().Set(`identifyLicenses`, js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return js.ValueOf(map[string]interface{}{`error`: `identifyLicenses expects the text to identify`})
+		}
+		spdx := len(args) > 1 && args[1].Truthy()
+		lics, evidence, err := identifyEvidence(strings.NewReader(args[0].String()))
+		if err != nil {
+			return js.ValueOf(map[string]interface{}{`error`: err.Error()})
+		}
+		var licenses []interface{}
+		for _, lic := range Uniq(lics) {
+			if spdx {
+				lic = lic.SPDX()
+			}
+			licenses = append(licenses, string(lic))
+		}
+		var found []interface{}
+		for _, ev := range evidence {
+			lic := ev.License
+			if spdx {
+				lic = lic.SPDX()
+			}
+			found = append(found, map[string]interface{}{
+				`license`:   string(lic),
+				`phrase`:    ev.Phrase,
+				`startLine`: ev.StartLine,
+				`endLine`:   ev.EndLine,
+			})
+		}
+		return js.ValueOf(map[string]interface{}{`licenses`: licenses, `evidence`: found})
+	}))
+	select {}
+}
@@ -0,0 +1,543 @@
+/*
+Copyright 2017 Comcast Corporation
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// yamlNode is a value parsed from the subset of YAML used by .weasel.yml:
+// block and flow mappings and sequences, plain and quoted scalars, and `|`
+// and `>` block scalars. Anchors, tags and multiple documents are not
+// supported.
+type yamlNode struct {
+	Line  int
+	Value string
+	List  []*yamlNode
+	Keys  []string /* Keys of a mapping, in the order they appear. */
+	Map   map[string]*yamlNode
+	/* KeyLines are the lines of Keys. */
+	KeyLines []int
+	IsMap    bool
+}
+
+// Get returns the value of a mapping key, or nil.
+func (n *yamlNode) Get(key string) *yamlNode {
+	if n == nil || n.Map == nil {
+		return nil
+	}
+	return n.Map[key]
+}
+
+// KeyLine returns the line of a mapping key, or 0.
+func (n *yamlNode) KeyLine(key string) int {
+	if n == nil {
+		return 0
+	}
+	for i, k := range n.Keys {
+		if k == key {
+			return n.KeyLines[i]
+		}
+	}
+	return 0
+}
+
+// Strings returns a sequence of scalars, or a lone scalar, as strings.
+func (n *yamlNode) Strings() []string {
+	if n == nil {
+		return nil
+	}
+	if n.List == nil && !n.IsMap {
+		if n.Value == `` {
+			return nil
+		}
+		return []string{n.Value}
+	}
+	var strs []string
+	for _, item := range n.List {
+		strs = append(strs, item.Value)
+	}
+	return strs
+}
+
+// Strings0 returns a scalar, or the first entry of a sequence.
+func (n *yamlNode) Strings0() string {
+	if strs := n.Strings(); len(strs) > 0 {
+		return strs[0]
+	}
+	return ``
+}
+
+type yamlError struct {
+	Line int
+	Msg  string
+}
+
+func (e *yamlError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type yamlLine struct {
+	Num    int
+	Indent int
+	Text   string
+	Raw    string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML parses a document into its root node, which is an empty mapping
+// if the document is empty.
+func parseYAML(doc string) (*yamlNode, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.Replace(doc, "\r\n", "\n", -1), "\n") {
+		text := stripYAMLComment(raw)
+		trimmed := strings.TrimLeft(text, ` `)
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, &yamlError{i + 1, "tabs may not be used for indentation"}
+		}
+		trimmed = strings.TrimSpace(trimmed)
+		if trimmed == `---` && len(p.lines) == 0 {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{Num: i + 1, Indent: len(text) - len(strings.TrimLeft(text, ` `)), Text: trimmed, Raw: raw})
+	}
+
+	p.skipBlank()
+	if p.pos == len(p.lines) {
+		return &yamlNode{Line: 1, IsMap: true, Map: map[string]*yamlNode{}}, nil
+	}
+	root, err := p.parseBlock(p.lines[p.pos].Indent)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, &yamlError{p.lines[p.pos].Num, "expected a sequence entry"}
+	}
+	return root, nil
+}
+
+// stripYAMLComment removes a trailing comment, which begins with a `#` at
+// the start of the line or after whitespace, outside of quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && p.lines[p.pos].Text == `` {
+		p.pos++
+	}
+}
+
+// parseBlock parses the mapping or sequence whose entries begin at indent.
+func (p *yamlParser) parseBlock(indent int) (*yamlNode, error) {
+	p.skipBlank()
+	line := p.lines[p.pos]
+	if line.Text == `-` || strings.HasPrefix(line.Text, `- `) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseSequence(indent int) (*yamlNode, error) {
+	node := &yamlNode{Line: p.lines[p.pos].Num, List: []*yamlNode{}}
+	for {
+		p.skipBlank()
+		if p.pos == len(p.lines) {
+			return node, nil
+		}
+		line := p.lines[p.pos]
+		if line.Indent < indent {
+			return node, nil
+		}
+		if line.Indent > indent {
+			return nil, &yamlError{line.Num, "unexpected indentation"}
+		}
+		if line.Text != `-` && !strings.HasPrefix(line.Text, `- `) {
+			/* The sequence was the value of a key at the same indentation. */
+			return node, nil
+		}
+
+		rest := strings.TrimSpace(line.Text[1:])
+		if rest == `` {
+			p.pos++
+			item, err := p.parseNested(indent, line.Num, false)
+			if err != nil {
+				return nil, err
+			}
+			node.List = append(node.List, item)
+			continue
+		}
+
+		if key, _, ok := splitYAMLKey(rest); ok && key != `` {
+			/* A mapping begun on the same line as its `-`. */
+			itemIndent := indent + (len(line.Text) - len(rest))
+			p.lines[p.pos] = yamlLine{Num: line.Num, Indent: itemIndent, Text: rest, Raw: line.Raw}
+			item, err := p.parseMapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			node.List = append(node.List, item)
+			continue
+		}
+
+		p.pos++
+		item, err := p.parseValue(rest, indent, line.Num)
+		if err != nil {
+			return nil, err
+		}
+		node.List = append(node.List, item)
+	}
+}
+
+func (p *yamlParser) parseMapping(indent int) (*yamlNode, error) {
+	node := &yamlNode{Line: p.lines[p.pos].Num, IsMap: true, Map: map[string]*yamlNode{}}
+	for {
+		p.skipBlank()
+		if p.pos == len(p.lines) {
+			return node, nil
+		}
+		line := p.lines[p.pos]
+		if line.Indent < indent {
+			return node, nil
+		}
+		if line.Indent > indent {
+			return nil, &yamlError{line.Num, "unexpected indentation"}
+		}
+
+		key, rest, ok := splitYAMLKey(line.Text)
+		if !ok {
+			return nil, &yamlError{line.Num, "expected `key: value`"}
+		}
+		if _, dup := node.Map[key]; dup {
+			return nil, &yamlError{line.Num, "duplicate key `" + key + "`"}
+		}
+		p.pos++
+
+		var value *yamlNode
+		var err error
+		if rest == `` {
+			value, err = p.parseNested(indent, line.Num, true)
+		} else {
+			value, err = p.parseValue(rest, indent, line.Num)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Keys = append(node.Keys, key)
+		node.KeyLines = append(node.KeyLines, line.Num)
+		node.Map[key] = value
+	}
+}
+
+// parseNested parses the block beneath a key or `-` with nothing after it,
+// which is null, represented as an empty scalar, if nothing is indented
+// beneath it. The sequence value of a key may be indented at the same
+// level as the key itself.
+func (p *yamlParser) parseNested(indent, num int, sameIndentSeq bool) (*yamlNode, error) {
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		next := p.lines[p.pos]
+		if next.Indent > indent {
+			return p.parseBlock(next.Indent)
+		}
+		if sameIndentSeq && next.Indent == indent && (next.Text == `-` || strings.HasPrefix(next.Text, `- `)) {
+			return p.parseSequence(indent)
+		}
+	}
+	return &yamlNode{Line: num}, nil
+}
+
+// splitYAMLKey splits `key: value`, with the key optionally quoted.
+func splitYAMLKey(text string) (string, string, bool) {
+	if len(text) > 0 && (text[0] == '"' || text[0] == '\'') {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return ``, ``, false
+		}
+		key := text[1 : end+1]
+		rest := text[end+2:]
+		if rest != `:` && !strings.HasPrefix(rest, `: `) {
+			return ``, ``, false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+	if len(text) > 0 && (text[0] == '[' || text[0] == '{') {
+		return ``, ``, false
+	}
+	if strings.HasSuffix(text, `:`) {
+		return strings.TrimSpace(text[:len(text)-1]), ``, true
+	}
+	i := strings.Index(text, `: `)
+	if i < 0 {
+		return ``, ``, false
+	}
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:]), true
+}
+
+// parseValue parses a value which follows a key or `-` on the same line.
+func (p *yamlParser) parseValue(text string, indent, num int) (*yamlNode, error) {
+	if text == `|` || text == `|-` || text == `>` || text == `>-` {
+		return p.parseBlockScalar(text, indent, num), nil
+	}
+	if text[0] == '[' || text[0] == '{' {
+		/* Flow collections may continue over several lines. */
+		for !flowBalanced(text) && p.pos < len(p.lines) {
+			text += ` ` + p.lines[p.pos].Text
+			p.pos++
+		}
+		node, rest, err := parseFlow(text, num)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != `` {
+			return nil, &yamlError{num, "unexpected `" + strings.TrimSpace(rest) + "`"}
+		}
+		return node, nil
+	}
+	value, err := parseScalar(text, num)
+	if err != nil {
+		return nil, err
+	}
+	return &yamlNode{Line: num, Value: value}, nil
+}
+
+func (p *yamlParser) parseBlockScalar(style string, indent, num int) *yamlNode {
+	var lines []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.Text != `` {
+			if line.Indent <= indent {
+				break
+			}
+			if blockIndent < 0 {
+				blockIndent = line.Indent
+			}
+		}
+		raw := strings.TrimRight(line.Raw, " \t")
+		if len(raw) >= blockIndent && blockIndent >= 0 {
+			raw = raw[blockIndent:]
+		} else {
+			raw = strings.TrimLeft(raw, ` `)
+		}
+		lines = append(lines, raw)
+		p.pos++
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == `` {
+		lines = lines[:len(lines)-1]
+	}
+
+	sep := "\n"
+	if style[0] == '>' {
+		sep = ` `
+	}
+	value := strings.Join(lines, sep)
+	if !strings.HasSuffix(style, `-`) && value != `` {
+		value += "\n"
+	}
+	return &yamlNode{Line: num, Value: value}
+}
+
+func flowBalanced(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// parseFlow parses a `[...]` or `{...}` collection at the start of text,
+// returning what follows it.
+func parseFlow(text string, num int) (*yamlNode, string, error) {
+	open := text[0]
+	close := byte(']')
+	node := &yamlNode{Line: num}
+	if open == '{' {
+		close = '}'
+		node.IsMap = true
+		node.Map = map[string]*yamlNode{}
+	} else {
+		node.List = []*yamlNode{}
+	}
+
+	text = strings.TrimSpace(text[1:])
+	for {
+		if text == `` {
+			return nil, ``, &yamlError{num, "unterminated flow collection"}
+		}
+		if text[0] == close {
+			return node, text[1:], nil
+		}
+
+		key := ``
+		if open == '{' {
+			k, rest, err := flowScalar(text, num, ":")
+			if err != nil {
+				return nil, ``, err
+			}
+			if rest == `` || rest[0] != ':' {
+				return nil, ``, &yamlError{num, "expected `:` after `" + k + "`"}
+			}
+			key = k
+			text = strings.TrimSpace(rest[1:])
+		}
+
+		var item *yamlNode
+		if text != `` && (text[0] == '[' || text[0] == '{') {
+			var err error
+			item, text, err = parseFlow(text, num)
+			if err != nil {
+				return nil, ``, err
+			}
+		} else {
+			value, rest, err := flowScalar(text, num, ",]}")
+			if err != nil {
+				return nil, ``, err
+			}
+			item = &yamlNode{Line: num, Value: value}
+			text = rest
+		}
+
+		if open == '{' {
+			if _, dup := node.Map[key]; dup {
+				return nil, ``, &yamlError{num, "duplicate key `" + key + "`"}
+			}
+			node.Keys = append(node.Keys, key)
+			node.KeyLines = append(node.KeyLines, num)
+			node.Map[key] = item
+		} else {
+			node.List = append(node.List, item)
+		}
+
+		text = strings.TrimSpace(text)
+		if text != `` && text[0] == ',' {
+			text = strings.TrimSpace(text[1:])
+		} else if text == `` || text[0] != close {
+			return nil, ``, &yamlError{num, "expected `,` or `" + string(close) + "`"}
+		}
+	}
+}
+
+// flowScalar reads a scalar inside a flow collection up to one of the
+// terminators, returning the remaining text.
+func flowScalar(text string, num int, terminators string) (string, string, error) {
+	text = strings.TrimSpace(text)
+	if text != `` && (text[0] == '"' || text[0] == '\'') {
+		end := closingQuote(text)
+		if end < 0 {
+			return ``, ``, &yamlError{num, "unterminated string"}
+		}
+		value, err := parseScalar(text[:end+1], num)
+		return value, strings.TrimSpace(text[end+1:]), err
+	}
+	i := strings.IndexAny(text, terminators)
+	if i < 0 {
+		i = len(text)
+	}
+	return strings.TrimSpace(text[:i]), text[i:], nil
+}
+
+// closingQuote returns the index of the quote ending the string which text
+// begins with, or -1.
+func closingQuote(text string) int {
+	q := text[0]
+	for i := 1; i < len(text); i++ {
+		if q == '"' && text[i] == '\\' {
+			i++
+			continue
+		}
+		if text[i] == q {
+			if q == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// parseScalar unquotes a plain, single or double quoted scalar.
+func parseScalar(text string, num int) (string, error) {
+	if text == `` || (text[0] != '"' && text[0] != '\'') {
+		if text == `~` || text == `null` {
+			return ``, nil
+		}
+		return text, nil
+	}
+	end := closingQuote(text)
+	if end != len(text)-1 {
+		return ``, &yamlError{num, "malformed string " + text}
+	}
+	if text[0] == '\'' {
+		return strings.Replace(text[1:end], `''`, `'`, -1), nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < end; i++ {
+		c := text[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '/':
+			b.WriteByte(text[i])
+		default:
+			return ``, &yamlError{num, "unsupported escape \\" + string(text[i])}
+		}
+	}
+	return b.String(), nil
+}